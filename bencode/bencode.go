@@ -0,0 +1,151 @@
+// Package bencode decodes bencoded data and parses .torrent metainfo, shared by
+// the bot and the Mini App.
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds list and dictionary nesting so hostile input cannot exhaust the stack.
+const maxDepth = 64
+
+// Decode parses a single bencoded value. Integers decode to int64, strings to
+// string, lists to []any and dictionaries to map[string]any.
+func Decode(data []byte) (any, error) {
+	d := decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("trailing data at offset %d", d.pos)
+	}
+	return v, nil
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nesting deeper than %d levels", maxDepth)
+	}
+	if d.pos >= len(d.data) {
+		return nil, errUnexpectedEnd
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.integer()
+	case c >= '0' && c <= '9':
+		return d.string()
+	case c == 'l':
+		return d.list(depth)
+	case c == 'd':
+		return d.dict(depth, nil)
+	default:
+		return nil, fmt.Errorf("unexpected byte %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *decoder) integer() (int64, error) {
+	start := d.pos + 1
+	end := d.indexFrom(start, 'e')
+	if end < 0 {
+		return 0, errUnexpectedEnd
+	}
+	digits := string(d.data[start:end])
+	if digits == "" || digits == "-0" || (len(digits) > 1 && digits[0] == '0') || (len(digits) > 2 && digits[:2] == "-0") {
+		return 0, fmt.Errorf("invalid integer %q at offset %d", digits, d.pos)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q at offset %d", digits, d.pos)
+	}
+	d.pos = end + 1
+	return n, nil
+}
+
+func (d *decoder) string() (string, error) {
+	colon := d.indexFrom(d.pos, ':')
+	if colon < 0 {
+		return "", errUnexpectedEnd
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid string length at offset %d", d.pos)
+	}
+	start := colon + 1
+	if length > len(d.data)-start {
+		return "", errUnexpectedEnd
+	}
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) list(depth int) ([]any, error) {
+	d.pos++
+	list := []any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, errUnexpectedEnd
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// dict decodes a dictionary; when raw is not nil it also records the encoded
+// bytes of every value, which the info hash needs.
+func (d *decoder) dict(depth int, raw map[string][]byte) (map[string]any, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 'd' {
+		return nil, errors.New("expected a dictionary")
+	}
+	d.pos++
+	dict := map[string]any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, errUnexpectedEnd
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+		if c := d.data[d.pos]; c < '0' || c > '9' {
+			return nil, fmt.Errorf("dictionary key is not a string at offset %d", d.pos)
+		}
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = v
+		if raw != nil {
+			raw[key] = d.data[start:d.pos]
+		}
+	}
+}
+
+func (d *decoder) indexFrom(from int, b byte) int {
+	if i := bytes.IndexByte(d.data[from:], b); i >= 0 {
+		return from + i
+	}
+	return -1
+}
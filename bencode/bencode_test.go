@@ -0,0 +1,53 @@
+package bencode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	v, err := Decode([]byte("d3:bar4:spam3:fooi42e4:listli-1e0:ee"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"bar":  "spam",
+		"foo":  int64(42),
+		"list": []any{int64(-1), ""},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode() = %#v, want %#v", v, want)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"i42",
+		"i-0e",
+		"i042e",
+		"ie",
+		"5:abc",
+		"l",
+		"di1ei2ee",
+		"i1ei2e",
+		"x",
+	} {
+		if _, err := Decode([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestDecode_DepthLimit(t *testing.T) {
+	deep := make([]byte, 0, 2*(maxDepth+2))
+	for i := 0; i < maxDepth+2; i++ {
+		deep = append(deep, 'l')
+	}
+	for i := 0; i < maxDepth+2; i++ {
+		deep = append(deep, 'e')
+	}
+	if _, err := Decode(deep); err == nil {
+		t.Error("expected nesting error")
+	}
+}
@@ -0,0 +1,159 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// File is a single file of a torrent.
+type File struct {
+	Path   string
+	Length int64
+}
+
+// Torrent is the part of a .torrent metainfo document shown to users.
+type Torrent struct {
+	Name string
+	// InfoHash is the hex SHA-1 of the info dictionary, or the hex SHA-256 for v2-only torrents.
+	InfoHash  string
+	Files     []File
+	TotalSize int64
+}
+
+// TopFiles returns up to n files, largest first.
+func (t Torrent) TopFiles(n int) []File {
+	files := append([]File(nil), t.Files...)
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Length > files[j].Length })
+	return files[:min(n, len(files))]
+}
+
+// ParseTorrent validates a .torrent metainfo document and extracts its name,
+// files and info hash.
+func ParseTorrent(data []byte) (Torrent, error) {
+	d := decoder{data: data}
+	raw := map[string][]byte{}
+	meta, err := d.dict(0, raw)
+	if err != nil {
+		return Torrent{}, fmt.Errorf("not a bencoded dictionary: %w", err)
+	}
+	if d.pos != len(d.data) {
+		return Torrent{}, fmt.Errorf("trailing data at offset %d", d.pos)
+	}
+
+	info, ok := meta["info"].(map[string]any)
+	if !ok {
+		return Torrent{}, errors.New("missing info dictionary")
+	}
+	name, ok := info["name"].(string)
+	if !ok || name == "" {
+		return Torrent{}, errors.New("missing torrent name")
+	}
+	if _, ok := info["piece length"].(int64); !ok {
+		return Torrent{}, errors.New("missing piece length")
+	}
+
+	t := Torrent{Name: name}
+	_, hasPieces := info["pieces"].(string)
+	switch {
+	case hasPieces:
+		sum := sha1.Sum(raw["info"])
+		t.InfoHash = hex.EncodeToString(sum[:])
+		t.Files, err = v1Files(info, name)
+	case info["meta version"] == int64(2):
+		sum := sha256.Sum256(raw["info"])
+		t.InfoHash = hex.EncodeToString(sum[:])
+		tree, ok := info["file tree"].(map[string]any)
+		if !ok {
+			return Torrent{}, errors.New("missing file tree")
+		}
+		err = v2Files(tree, "", &t.Files, 0)
+	default:
+		return Torrent{}, errors.New("missing pieces")
+	}
+	if err != nil {
+		return Torrent{}, err
+	}
+	if len(t.Files) == 0 {
+		return Torrent{}, errors.New("torrent has no files")
+	}
+
+	for _, f := range t.Files {
+		t.TotalSize += f.Length
+	}
+	return t, nil
+}
+
+func v1Files(info map[string]any, name string) ([]File, error) {
+	if length, ok := info["length"].(int64); ok {
+		if length < 0 {
+			return nil, errors.New("negative file length")
+		}
+		return []File{{Path: name, Length: length}}, nil
+	}
+
+	list, ok := info["files"].([]any)
+	if !ok {
+		return nil, errors.New("missing length or files")
+	}
+	files := make([]File, 0, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid file entry")
+		}
+		length, ok := entry["length"].(int64)
+		if !ok || length < 0 {
+			return nil, errors.New("invalid file length")
+		}
+		parts, ok := entry["path"].([]any)
+		if !ok || len(parts) == 0 {
+			return nil, errors.New("invalid file path")
+		}
+		elems := make([]string, 0, len(parts))
+		for _, p := range parts {
+			s, ok := p.(string)
+			if !ok {
+				return nil, errors.New("invalid file path")
+			}
+			elems = append(elems, s)
+		}
+		// BEP 47 padding files are not real content.
+		if attr, _ := entry["attr"].(string); strings.Contains(attr, "p") {
+			continue
+		}
+		files = append(files, File{Path: path.Join(elems...), Length: length})
+	}
+	return files, nil
+}
+
+// v2Files walks a BEP 52 file tree, where a file is a dictionary with an empty key.
+func v2Files(tree map[string]any, prefix string, files *[]File, depth int) error {
+	if depth > maxDepth {
+		return errors.New("file tree too deep")
+	}
+	for name, node := range tree {
+		dir, ok := node.(map[string]any)
+		if !ok {
+			return errors.New("invalid file tree")
+		}
+		if leaf, ok := dir[""].(map[string]any); ok {
+			length, ok := leaf["length"].(int64)
+			if !ok || length < 0 {
+				return errors.New("invalid file length")
+			}
+			*files = append(*files, File{Path: path.Join(prefix, name), Length: length})
+			continue
+		}
+		if err := v2Files(dir, path.Join(prefix, name), files, depth+1); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(*files, func(i, j int) bool { return (*files)[i].Path < (*files)[j].Path })
+	return nil
+}
@@ -0,0 +1,75 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseTorrent_SingleFile(t *testing.T) {
+	info := "d6:lengthi1024e4:name9:movie.mkv12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	data := []byte("d8:announce14:http://tracker4:info" + info + "e")
+
+	torrent, err := ParseTorrent(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sum := sha1.Sum([]byte(info))
+	if torrent.InfoHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("InfoHash = %s", torrent.InfoHash)
+	}
+	if torrent.Name != "movie.mkv" || torrent.TotalSize != 1024 || len(torrent.Files) != 1 {
+		t.Errorf("unexpected torrent %#v", torrent)
+	}
+}
+
+func TestParseTorrent_MultiFile(t *testing.T) {
+	data := []byte("d4:infod5:filesl" +
+		"d6:lengthi10e4:pathl5:a.txteed" +
+		"4:attr1:p6:lengthi5e4:pathl4:.pad1:0eed" +
+		"6:lengthi300e4:pathl3:sub5:b.mkveee" +
+		"4:name4:Show12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee")
+
+	torrent, err := ParseTorrent(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(torrent.Files) != 2 || torrent.TotalSize != 310 {
+		t.Fatalf("expected padding to be skipped, got %#v", torrent.Files)
+	}
+	top := torrent.TopFiles(1)
+	if len(top) != 1 || top[0].Path != "sub/b.mkv" {
+		t.Errorf("TopFiles(1) = %#v", top)
+	}
+	if len(torrent.TopFiles(5)) != 2 {
+		t.Error("TopFiles should not return more files than the torrent has")
+	}
+}
+
+func TestParseTorrent_V2(t *testing.T) {
+	data := []byte("d4:infod9:file treed3:dird5:b.mkvd0:d6:lengthi7eeee5:a.txtd0:d6:lengthi3eeee" +
+		"12:meta versioni2e4:name2:v212:piece lengthi16384eee")
+
+	torrent, err := ParseTorrent(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(torrent.InfoHash) != 64 || torrent.TotalSize != 10 || torrent.Files[1].Path != "dir/b.mkv" {
+		t.Errorf("unexpected torrent %#v", torrent)
+	}
+}
+
+func TestParseTorrent_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"%PDF-1.4",
+		"le",
+		"d8:announce3:urle",
+		"d4:infod4:name1:x12:piece lengthi1e6:pieces0:ee",
+		"d4:infod6:lengthi1e12:piece lengthi1e6:pieces0:ee",
+		"d4:infod6:lengthi1e4:name1:x12:piece lengthi1e6:pieces0:eeXX",
+	} {
+		if _, err := ParseTorrent([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package botapi calls Telegram Bot API methods that the telegram client
+// library does not expose, such as sending a message and getting its ID back.
+package botapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const defaultBaseURL = "https://api.telegram.org"
+
+// Client is a minimal Bot API client.
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+	// upload has a longer timeout for sending files.
+	upload *http.Client
+	// poll outlasts the long-polling timeout of getUpdates.
+	poll *http.Client
+}
+
+// New creates a client for the bot token.
+func New(token string) *Client {
+	return NewWithBaseURL(token, defaultBaseURL)
+}
+
+// NewWithBaseURL creates a client for a Bot API server other than
+// api.telegram.org, such as a local Bot API server.
+func NewWithBaseURL(token string, baseURL string) *Client {
+	return &Client{
+		token:   token,
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		upload:  &http.Client{Timeout: 10 * time.Minute},
+		poll:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Message is a sendMessage request.
+type Message struct {
+	ChatID              int64  `json:"chat_id"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode,omitempty"`
+	ReplyMarkup         any    `json:"reply_markup,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+	// ReplyTo makes the message a reply; nil sends a standalone message.
+	ReplyTo *ReplyParameters `json:"reply_parameters,omitempty"`
+}
+
+// ReplyParameters describes the message being replied to.
+type ReplyParameters struct {
+	MessageID int64 `json:"message_id"`
+	// AllowSendingWithoutReply sends the message even if the replied one was deleted.
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply,omitempty"`
+}
+
+// SendMessage sends a message and returns its message ID.
+func (c *Client) SendMessage(msg Message) (int64, error) {
+	var sent struct {
+		MessageID int64 `json:"message_id"`
+	}
+	if err := c.call("sendMessage", msg, &sent); err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// editMessageText is an editMessageText request.
+type editMessageText struct {
+	ChatID      int64  `json:"chat_id"`
+	MessageID   int64  `json:"message_id"`
+	Text        string `json:"text"`
+	ReplyMarkup any    `json:"reply_markup,omitempty"`
+}
+
+// EditMessageText replaces the text and keyboard of a message the bot sent;
+// a nil markup removes the keyboard.
+func (c *Client) EditMessageText(chatID int64, messageID int64, text string, markup any) error {
+	return c.call("editMessageText", editMessageText{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ReplyMarkup: markup,
+	}, nil)
+}
+
+// Error is an unsuccessful Bot API response.
+type Error struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// IsPermanent reports whether a request failed in a way that retrying cannot
+// fix, such as a chat that no longer exists or a user who blocked the bot.
+func IsPermanent(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusBadRequest || apiErr.Code == http.StatusForbidden
+}
+
+func (c *Client) call(method string, payload any, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.post(context.Background(), c.http, method, "application/json", bytes.NewReader(body), result)
+}
+
+// post sends a request body to a Bot API method and decodes the result.
+func (c *Client) post(ctx context.Context, client *http.Client, method string, contentType string, body io.Reader, result any) error {
+	url := fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("telegram %s: invalid request", method)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL contains the bot token, keep it out of errors and logs.
+		return fmt.Errorf("telegram %s: request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Ok          bool            `json:"ok"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: invalid response (%s): %w", method, resp.Status, err)
+	}
+	if !envelope.Ok {
+		return &Error{Method: method, Code: envelope.ErrorCode, Description: envelope.Description}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
@@ -0,0 +1,192 @@
+package botapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := New("TOKEN")
+	c.baseURL = srv.URL
+	return c
+}
+
+func TestSendMessage(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var msg map[string]any
+		json.NewDecoder(r.Body).Decode(&msg)
+		if msg["chat_id"] != float64(42) || msg["text"] != "hi" || msg["disable_notification"] != true {
+			t.Errorf("unexpected payload %v", msg)
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":7}}`))
+	})
+
+	id, err := c.SendMessage(Message{ChatID: 42, Text: "hi", DisableNotification: true})
+	if err != nil || id != 7 {
+		t.Errorf("SendMessage() = %d, %v", id, err)
+	}
+}
+
+func TestSendMessage_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	})
+
+	_, err := c.SendMessage(Message{ChatID: 1, Text: "x"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 400 || apiErr.Description != "Bad Request: chat not found" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEditMessageText(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/editMessageText" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var msg map[string]any
+		json.NewDecoder(r.Body).Decode(&msg)
+		if msg["chat_id"] != float64(42) || msg["message_id"] != float64(7) || msg["text"] != "done" {
+			t.Errorf("unexpected payload %v", msg)
+		}
+		if _, ok := msg["reply_markup"]; ok {
+			t.Errorf("expected no keyboard, got %v", msg["reply_markup"])
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	})
+
+	if err := c.EditMessageText(42, 7, "done", nil); err != nil {
+		t.Errorf("EditMessageText() = %v", err)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&Error{Method: "sendMessage", Code: 403, Description: "Forbidden: bot was blocked by the user"}, true},
+		{&Error{Method: "sendMessage", Code: 400, Description: "Bad Request: chat not found"}, true},
+		{&Error{Method: "sendMessage", Code: 429, Description: "Too Many Requests"}, false},
+		{errors.New("telegram sendMessage: request failed"), false},
+	}
+	for _, tt := range tests {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSendAudio(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/sendAudio" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("invalid form: %v", err)
+		}
+		if r.FormValue("chat_id") != "42" || r.FormValue("caption") != "Album" || r.FormValue("disable_notification") != "true" {
+			t.Errorf("unexpected fields %v", r.MultipartForm.Value)
+		}
+		file, header, err := r.FormFile("audio")
+		if err != nil {
+			t.Fatalf("missing file: %v", err)
+		}
+		content, _ := io.ReadAll(file)
+		if header.Filename != "track.mp3" || string(content) != "ID3 data" {
+			t.Errorf("unexpected file %s %q", header.Filename, content)
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":8}}`))
+	})
+
+	err := c.SendAudio(Upload{
+		ChatID:              42,
+		Name:                "track.mp3",
+		Content:             strings.NewReader("ID3 data"),
+		Caption:             "Album",
+		DisableNotification: true,
+	})
+	if err != nil {
+		t.Errorf("SendAudio() = %v", err)
+	}
+}
+
+func TestSendDocument_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"ok":false,"error_code":413,"description":"Request Entity Too Large"}`))
+	})
+
+	err := c.SendDocument(Upload{ChatID: 1, Name: "big.zip", Content: strings.NewReader("zip")})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 413 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestGetUpdates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/getUpdates" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var params map[string]any
+		json.NewDecoder(r.Body).Decode(&params)
+		if params["offset"] != float64(10) || params["timeout"] != float64(50) {
+			t.Errorf("unexpected params %v", params)
+		}
+		w.Write([]byte(`{"ok":true,"result":[{"update_id":10,"message":{"message_id":1,"text":"/list","chat":{"id":42}}}]}`))
+	})
+
+	updates, err := c.GetUpdates(context.Background(), 10, 50*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updates) != 1 || updates[0].UpdateId != 10 || updates[0].Message.Text != "/list" {
+		t.Errorf("unexpected updates %+v", updates)
+	}
+}
+
+func TestSetWebhook_Certificate(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/setWebhook" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("invalid form: %v", err)
+		}
+		if r.FormValue("url") != "https://bot.example.org:8443/" || r.FormValue("secret_token") != "s3cret" {
+			t.Errorf("unexpected fields %v", r.MultipartForm.Value)
+		}
+		file, _, err := r.FormFile("certificate")
+		if err != nil {
+			t.Fatalf("missing certificate: %v", err)
+		}
+		if content, _ := io.ReadAll(file); string(content) != "PEM" {
+			t.Errorf("unexpected certificate %q", content)
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	})
+
+	err := c.SetWebhook(Webhook{
+		URL:         "https://bot.example.org:8443/",
+		SecretToken: "s3cret",
+		Certificate: strings.NewReader("PEM"),
+	})
+	if err != nil {
+		t.Errorf("SetWebhook() = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package botapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/minya/telegram"
+)
+
+// GetUpdates long-polls for updates starting at offset, the update ID after the
+// last one handled. Telegram holds the request for up to timeout when there
+// are none; cancelling ctx ends the wait.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]telegram.Update, error) {
+	params := struct {
+		Offset  int64 `json:"offset"`
+		Timeout int   `json:"timeout"`
+	}{offset, int(timeout.Seconds())}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var updates []telegram.Update
+	if err := c.post(ctx, c.poll, "getUpdates", "application/json", bytes.NewReader(body), &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// DeleteWebhook removes the webhook so updates can be fetched with
+// GetUpdates. Pending updates are kept.
+func (c *Client) DeleteWebhook() error {
+	return c.call("deleteWebhook", struct{}{}, nil)
+}
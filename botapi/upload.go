@@ -0,0 +1,72 @@
+package botapi
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"strconv"
+)
+
+// MaxUploadSize is the largest file bots can upload through the Bot API.
+const MaxUploadSize = 50 * 1024 * 1024
+
+// Upload is a file sent with sendDocument or sendAudio.
+type Upload struct {
+	ChatID              int64
+	Name                string
+	Content             io.Reader
+	Caption             string
+	DisableNotification bool
+}
+
+// SendDocument sends a file as a document.
+func (c *Client) SendDocument(upload Upload) error {
+	return c.sendFile("sendDocument", "document", upload)
+}
+
+// SendAudio sends a file that Telegram shows in its music player.
+func (c *Client) SendAudio(upload Upload) error {
+	return c.sendFile("sendAudio", "audio", upload)
+}
+
+// sendFile streams the upload as multipart form data, so large files are
+// never held in memory.
+func (c *Client) sendFile(method string, field string, upload Upload) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		err := writeUploadForm(form, field, upload)
+		if closeErr := form.Close(); err == nil {
+			err = closeErr
+		}
+		writer.CloseWithError(err)
+	}()
+
+	err := c.post(context.Background(), c.upload, method, form.FormDataContentType(), body, nil)
+	// Unblock the writer if the request ended before the body was read
+	body.Close()
+	return err
+}
+
+func writeUploadForm(form *multipart.Writer, field string, upload Upload) error {
+	fields := map[string]string{"chat_id": strconv.FormatInt(upload.ChatID, 10)}
+	if upload.Caption != "" {
+		fields["caption"] = upload.Caption
+	}
+	if upload.DisableNotification {
+		fields["disable_notification"] = "true"
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile(field, upload.Name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, upload.Content)
+	return err
+}
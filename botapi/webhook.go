@@ -0,0 +1,56 @@
+package botapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+)
+
+// Webhook is a setWebhook request.
+type Webhook struct {
+	URL         string
+	SecretToken string
+	// Certificate is the listener's public key certificate in PEM, uploaded so
+	// Telegram accepts a self-signed one; nil when Telegram checks it against
+	// the trusted CAs, e.g. behind a tunnel.
+	Certificate io.Reader
+}
+
+// SetWebhook has Telegram post updates to the webhook's URL.
+func (c *Client) SetWebhook(webhook Webhook) error {
+	if webhook.Certificate == nil {
+		params := struct {
+			URL         string `json:"url"`
+			SecretToken string `json:"secret_token,omitempty"`
+		}{webhook.URL, webhook.SecretToken}
+		return c.call("setWebhook", params, nil)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := writeWebhookForm(form, webhook); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+	return c.post(context.Background(), c.http, "setWebhook", form.FormDataContentType(), &body, nil)
+}
+
+func writeWebhookForm(form *multipart.Writer, webhook Webhook) error {
+	if err := form.WriteField("url", webhook.URL); err != nil {
+		return err
+	}
+	if webhook.SecretToken != "" {
+		if err := form.WriteField("secret_token", webhook.SecretToken); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("certificate", "certificate.pem")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, webhook.Certificate)
+	return err
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/minya/tgtorrentbot/config"
 )
 
 func setEnvVars(t *testing.T, vars map[string]string) {
@@ -38,9 +43,9 @@ func TestLoadConfig_AllFields(t *testing.T) {
 		"TGT_ALLOWED_USERS":       "123,456",
 	})
 
-	cfg, err := loadConfig()
+	cfg, err := loadConfig(nil)
 	if err != nil {
-		t.Fatalf("loadConfig() error: %v", err)
+		t.Fatalf("loadConfig(nil) error: %v", err)
 	}
 
 	if cfg.BotToken != "test-bot-token" {
@@ -73,9 +78,9 @@ func TestLoadConfig_IncompletePathDefault(t *testing.T) {
 		"TGT_INCOMPLETE_PATH": "",
 	})
 
-	cfg, err := loadConfig()
+	cfg, err := loadConfig(nil)
 	if err != nil {
-		t.Fatalf("loadConfig() error: %v", err)
+		t.Fatalf("loadConfig(nil) error: %v", err)
 	}
 
 	expected := "/downloads/incomplete"
@@ -92,9 +97,9 @@ func TestLoadConfig_IncompletePathEmptyWhenNoDownloadPath(t *testing.T) {
 	})
 
 	// Missing TGT_DOWNLOADPATH is a required field, expect error
-	_, err := loadConfig()
+	_, err := loadConfig(nil)
 	if err == nil {
-		t.Fatal("loadConfig() should return error when TGT_DOWNLOADPATH is empty")
+		t.Fatal("loadConfig(nil) should return error when TGT_DOWNLOADPATH is empty")
 	}
 }
 
@@ -105,9 +110,9 @@ func TestLoadConfig_JellyfinOptional(t *testing.T) {
 		"TGT_JELLYFIN_API_KEY": "",
 	})
 
-	cfg, err := loadConfig()
+	cfg, err := loadConfig(nil)
 	if err != nil {
-		t.Fatalf("loadConfig() error: %v", err)
+		t.Fatalf("loadConfig(nil) error: %v", err)
 	}
 
 	if cfg.JellyfinURL != "" {
@@ -122,9 +127,9 @@ func TestLoadConfig_BotTokenTrimmed(t *testing.T) {
 	setRequiredEnvVars(t)
 	t.Setenv("TGT_BOTTOKEN", "  token-with-spaces  ")
 
-	cfg, err := loadConfig()
+	cfg, err := loadConfig(nil)
 	if err != nil {
-		t.Fatalf("loadConfig() error: %v", err)
+		t.Fatalf("loadConfig(nil) error: %v", err)
 	}
 
 	if cfg.BotToken != "token-with-spaces" {
@@ -134,9 +139,9 @@ func TestLoadConfig_BotTokenTrimmed(t *testing.T) {
 
 func TestLoadConfig_MissingRequired(t *testing.T) {
 	// No env vars set at all
-	_, err := loadConfig()
+	_, err := loadConfig(nil)
 	if err == nil {
-		t.Fatal("loadConfig() should return error when required vars are missing")
+		t.Fatal("loadConfig(nil) should return error when required vars are missing")
 	}
 }
 
@@ -144,8 +149,41 @@ func TestLoadConfig_InvalidAllowedUsers(t *testing.T) {
 	setRequiredEnvVars(t)
 	t.Setenv("TGT_ALLOWED_USERS", "alice,bob")
 
-	_, err := loadConfig()
+	_, err := loadConfig(nil)
 	if err == nil {
-		t.Fatal("loadConfig() should return error for invalid TGT_ALLOWED_USERS")
+		t.Fatal("loadConfig(nil) should return error for invalid TGT_ALLOWED_USERS")
+	}
+}
+
+func TestLoadConfig_SettingsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	err := os.WriteFile(path, []byte(`{
+		"botToken": "file-token",
+		"downloadPath": "/downloads",
+		"allowedUsers": [123],
+		"transmissionRPC": {"address": "http://localhost:9091/rpc", "user": "admin", "password": "secret"}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("webapp", flag.ContinueOnError)
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-settings", path}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TGT_RPC_PASSWORD", "from-env")
+
+	cfg, err := loadConfig(flags)
+	if err != nil {
+		t.Fatalf("loadConfig() error: %v", err)
+	}
+	if cfg.BotToken != "file-token" || cfg.TransmissionAddr != "http://localhost:9091/rpc" {
+		t.Errorf("expected values from the settings file, got %+v", cfg)
+	}
+	if cfg.TransmissionPassword != "from-env" {
+		t.Errorf("TransmissionPassword = %q, want the environment to override the file", cfg.TransmissionPassword)
+	}
+	if cfg.IncompletePath != "/downloads/incomplete" {
+		t.Errorf("IncompletePath = %q, want %q", cfg.IncompletePath, "/downloads/incomplete")
 	}
 }
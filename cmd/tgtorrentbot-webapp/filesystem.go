@@ -4,13 +4,28 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/minya/tgtorrentbot/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// scanDuration times the directory scans behind the Mini App's item list, by
+// category or "incomplete".
+var scanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "tgt_webapp_filesystem_scan_duration_seconds",
+	Help:    "Duration of the filesystem scans of the item list, by directory.",
+	Buckets: metrics.DefaultBuckets,
+}, []string{"dir"})
+
 // FsItem represents a media item found on the filesystem.
 type FsItem struct {
 	Name         string
 	Size         int64
 	IsIncomplete bool
+	// IsExtracted is set for items whose archives the bot has extracted.
+	IsExtracted bool
 }
 
 // filesystemScanner scans download and incomplete directories for media items.
@@ -44,8 +59,12 @@ func scanDir(dir string, incomplete bool) ([]FsItem, error) {
 	var items []FsItem
 	for _, entry := range entries {
 		var size int64
+		fullPath := filepath.Join(dir, entry.Name())
 		if entry.IsDir() {
-			fullPath := filepath.Join(dir, entry.Name())
+			// A torrent that is a single archive is listed once, as the archive
+			if source, ok := extract.ExtractedFrom(fullPath); ok && fileExists(filepath.Join(dir, source)) {
+				continue
+			}
 			size, err = dirSize(fullPath)
 			if err != nil {
 				size = 0
@@ -62,11 +81,17 @@ func scanDir(dir string, incomplete bool) ([]FsItem, error) {
 			Name:         entry.Name(),
 			Size:         size,
 			IsIncomplete: incomplete,
+			IsExtracted:  extract.IsExtracted(fullPath),
 		})
 	}
 	return items, nil
 }
 
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
 // dirSize recursively computes the total size of all files in a directory.
 func dirSize(path string) (int64, error) {
 	var total int64
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/minya/tgtorrentbot/extract"
 )
 
 func TestScanCategory(t *testing.T) {
@@ -153,3 +155,39 @@ func TestScanCategoryNestedFiles(t *testing.T) {
 		t.Errorf("expected size 300, got %d", items[0].Size)
 	}
 }
+
+func TestScanCategoryExtracted(t *testing.T) {
+	tmp := t.TempDir()
+	musicDir := filepath.Join(tmp, "music")
+	os.MkdirAll(filepath.Join(musicDir, "Album"), 0o755)
+	os.MkdirAll(filepath.Join(musicDir, "Single"), 0o755)
+	os.WriteFile(filepath.Join(musicDir, "Album", extract.MarkerFile), []byte(`{"archives":["album.rar"]}`), 0o644)
+
+	scanner := &filesystemScanner{downloadPath: tmp}
+	items, err := scanner.ScanCategory("music")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, item := range items {
+		if want := item.Name == "Album"; item.IsExtracted != want {
+			t.Errorf("%s: IsExtracted = %v, want %v", item.Name, item.IsExtracted, want)
+		}
+	}
+}
+
+func TestScanCategoryExtractedSingleArchive(t *testing.T) {
+	tmp := t.TempDir()
+	moviesDir := filepath.Join(tmp, "movies")
+	os.MkdirAll(filepath.Join(moviesDir, "Movie"), 0o755)
+	os.WriteFile(filepath.Join(moviesDir, "Movie.rar"), make([]byte, 100), 0o644)
+	os.WriteFile(filepath.Join(moviesDir, "Movie", extract.MarkerFile), []byte(`{"archives":["Movie.rar"],"source":"Movie.rar"}`), 0o644)
+
+	scanner := &filesystemScanner{downloadPath: tmp}
+	items, err := scanner.ScanCategory("movies")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "Movie.rar" || !items[0].IsExtracted {
+		t.Errorf("expected only the archive, marked as extracted, got %+v", items)
+	}
+}
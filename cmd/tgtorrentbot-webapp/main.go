@@ -4,34 +4,39 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/fs"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"slices"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/minya/logger"
 	"github.com/minya/rutracker"
+	"github.com/minya/tgtorrentbot/bencode"
+	"github.com/minya/tgtorrentbot/config"
+	"github.com/minya/tgtorrentbot/jellyfin"
+	"github.com/minya/tgtorrentbot/magnet"
+	"github.com/minya/tgtorrentbot/metrics"
+	"github.com/minya/tgtorrentbot/searchquery"
+	"github.com/minya/tgtorrentbot/torrentlink"
 	"github.com/odwrtw/transmission"
 )
 
 //go:embed static
 var staticFiles embed.FS
 
-// relativeDownloadRe matches the relative URL format returned by the rutracker library's Find().
-var relativeDownloadRe = regexp.MustCompile(`^dl\.php\?t=\d+$`)
-
 // validCategories is the single source of truth for valid download categories.
 var validCategories = []string{"movies", "shows", "music", "musicvideos", "audiobooks", "others"}
 
+// defaultMetricsAddress is where the metrics are served when no address is set.
+const defaultMetricsAddress = config.DefaultWebAppMetricsAddress
+
 type Config struct {
 	BotToken             string
 	TransmissionAddr     string
@@ -43,59 +48,58 @@ type Config struct {
 	LogLevel             string
 	JellyfinURL          string
 	JellyfinAPIKey       string
+	JellyfinMediaPath    string
 	IncompletePath       string
 	AllowedUsers         []int64
+	TrustedTorrentHosts  []string
+	MetricsAddress       string
 }
 
-func loadConfig() (Config, error) {
-	downloadPath := os.Getenv("TGT_DOWNLOADPATH")
-	incompletePath := os.Getenv("TGT_INCOMPLETE_PATH")
-	if incompletePath == "" && downloadPath != "" {
-		incompletePath = filepath.Join(downloadPath, "incomplete")
-	}
-
-	var config Config
-	config.BotToken = strings.TrimSpace(os.Getenv("TGT_BOTTOKEN"))
-	config.TransmissionAddr = os.Getenv("TGT_RPC_ADDR")
-	config.TransmissionUser = os.Getenv("TGT_RPC_USER")
-	config.TransmissionPassword = os.Getenv("TGT_RPC_PASSWORD")
-	config.RutrackerUsername = os.Getenv("TGT_RUTRACKER_USERNAME")
-	config.RutrackerPassword = os.Getenv("TGT_RUTRACKER_PASSWORD")
-	config.DownloadPath = downloadPath
-	config.LogLevel = os.Getenv("TGT_LOGLEVEL")
-	config.JellyfinURL = os.Getenv("TGT_JELLYFIN_URL")
-	config.JellyfinAPIKey = os.Getenv("TGT_JELLYFIN_API_KEY")
-	config.IncompletePath = incompletePath
-
-	var problems []string
-	if config.BotToken == "" {
-		problems = append(problems, "TGT_BOTTOKEN is not set")
-	}
-	if config.DownloadPath == "" {
-		problems = append(problems, "TGT_DOWNLOADPATH is not set")
-	}
-
-	allowedUsersRaw := os.Getenv("TGT_ALLOWED_USERS")
-	if strings.TrimSpace(allowedUsersRaw) == "" {
-		problems = append(problems, "TGT_ALLOWED_USERS is not set")
-	} else {
-		allowedUsers, err := parseAllowedUsers(allowedUsersRaw)
-		if err != nil {
-			problems = append(problems, fmt.Sprintf("TGT_ALLOWED_USERS: %v", err))
-		}
-		config.AllowedUsers = allowedUsers
-	}
+// requiredSettings are the settings the Mini App can't run without.
+var requiredSettings = []string{
+	"TGT_BOTTOKEN",
+	"TGT_DOWNLOADPATH",
+	"TGT_ALLOWED_USERS",
+}
 
-	if len(problems) > 0 {
-		return config, fmt.Errorf("environment config problems: %v", problems)
-	}
-	return config, nil
+// loadConfig reads the settings shared with the bot: the settings file, the
+// environment and the command line flags.
+func loadConfig(flags *config.Flags) (Config, error) {
+	settings, err := config.Load(config.Options{
+		Flags:    flags,
+		Required: requiredSettings,
+		ValidCategory: func(category string) bool {
+			return slices.Contains(validCategories, category)
+		},
+	})
+
+	incompletePath := settings.IncompletePath
+	if incompletePath == "" && settings.DownloadPath != "" {
+		incompletePath = filepath.Join(settings.DownloadPath, "incomplete")
+	}
+	return Config{
+		BotToken:             settings.BotToken,
+		TransmissionAddr:     settings.TransmissionRPC.Address,
+		TransmissionUser:     settings.TransmissionRPC.User,
+		TransmissionPassword: settings.TransmissionRPC.Password,
+		RutrackerUsername:    settings.RutrackerConfig.Username,
+		RutrackerPassword:    settings.RutrackerConfig.Password,
+		DownloadPath:         settings.DownloadPath,
+		LogLevel:             settings.LogLevel,
+		JellyfinURL:          settings.JellyfinURL,
+		JellyfinAPIKey:       settings.JellyfinAPIKey,
+		JellyfinMediaPath:    settings.JellyfinMediaPath,
+		IncompletePath:       incompletePath,
+		AllowedUsers:         settings.AllowedUsers,
+		TrustedTorrentHosts:  settings.TrustedTorrentHosts,
+		MetricsAddress:       settings.WebAppMetricsAddress,
+	}, err
 }
 
 type App struct {
 	config             Config
 	transmissionClient *transmission.Client
-	jellyfinClient     *jellyfinClient
+	jellyfinClient     *jellyfin.Client
 }
 
 type httpHandlerFunc func(http.ResponseWriter, *http.Request)
@@ -132,48 +136,30 @@ func (app *App) makeHandler(allowedMethods []string, handler appHandlerFunc) htt
 	}
 }
 
-func parseAllowedUsers(s string) ([]int64, error) {
-	var result []int64
-	var invalid []string
-	for _, part := range strings.Split(s, ",") {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		id, err := strconv.ParseInt(part, 10, 64)
-		if err != nil {
-			invalid = append(invalid, part)
-			continue
-		}
-		result = append(result, id)
-	}
-	if len(invalid) > 0 {
-		return result, fmt.Errorf("invalid user IDs: %v", invalid)
-	}
-	return result, nil
-}
-
 func main() {
+	configFlags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	config, err := loadConfig(configFlags)
 	logLevel := "info"
-	if val, exists := os.LookupEnv("TGT_LOGLEVEL"); exists {
-		logLevel = val
+	if config.LogLevel != "" {
+		logLevel = config.LogLevel
 	}
 	logger.InitLogger(logger.Config{
 		Level:  logLevel,
 		Pretty: true,
 		Output: os.Stdout,
 	})
-
-	config, err := loadConfig()
 	if err != nil {
 		logger.Error(err, "Failed to load config")
 		os.Exit(1)
 	}
 
 	transmissionClient, err := transmission.New(transmission.Config{
-		Address:  config.TransmissionAddr,
-		User:     config.TransmissionUser,
-		Password: config.TransmissionPassword,
+		Address:    config.TransmissionAddr,
+		User:       config.TransmissionUser,
+		Password:   config.TransmissionPassword,
+		HTTPClient: metrics.TransmissionClient(),
 	})
 	if err != nil {
 		logger.Error(err, "Failed to create transmission client")
@@ -183,11 +169,13 @@ func main() {
 	app := &App{
 		config:             config,
 		transmissionClient: transmissionClient,
-		jellyfinClient:     newJellyfinClient(config.JellyfinURL, config.JellyfinAPIKey),
+		jellyfinClient:     jellyfin.New(config.JellyfinURL, config.JellyfinAPIKey, config.JellyfinMediaPath),
 	}
+	metrics.Serve(config.MetricsAddress, defaultMetricsAddress)
 
 	http.HandleFunc("/api/torrents", app.makeHandler([]string{http.MethodGet}, app.handleTorrents))
 http.HandleFunc("/api/torrents/download", app.makeHandler([]string{http.MethodPost}, app.handleDownloadTorrent))
+	http.HandleFunc("/api/torrents/magnet", app.makeHandler([]string{http.MethodPost}, app.handleAddMagnet))
 	http.HandleFunc("/api/search", app.makeHandler([]string{http.MethodGet}, app.handleSearch))
 	http.HandleFunc("/api/items", app.makeHandler([]string{http.MethodGet}, app.handleUnifiedItems))
 	http.HandleFunc("/api/items/", app.makeHandler([]string{http.MethodDelete}, app.handleItemDelete))
@@ -282,12 +270,10 @@ func (app *App) handleDownloadTorrent(userID int64, w http.ResponseWriter, r *ht
 		return
 	}
 
-	parsedURL, err := url.Parse(req.DownloadURL)
-	isAbsoluteRutracker := err == nil && (parsedURL.Host == "rutracker.org" || parsedURL.Host == "www.rutracker.org")
-	isRelativeDownload := err == nil && parsedURL.Host == "" && relativeDownloadRe.MatchString(req.DownloadURL)
-	if !isAbsoluteRutracker && !isRelativeDownload {
-		logger.Warn("Invalid downloadUrl: %s", req.DownloadURL)
-		http.Error(w, `{"error": "invalid downloadUrl: must be a rutracker.org URL"}`, http.StatusBadRequest)
+	link, err := torrentlink.Resolve(req.DownloadURL, app.config.TrustedTorrentHosts)
+	if err != nil {
+		logger.Warn("Invalid downloadUrl %s: %v", req.DownloadURL, err)
+		writeJSONError(w, fmt.Sprintf("invalid downloadUrl: %v", err), http.StatusBadRequest)
 		return
 	}
 
@@ -303,38 +289,89 @@ func (app *App) handleDownloadTorrent(userID int64, w http.ResponseWriter, r *ht
 		return
 	}
 
+	torrentData, err := app.fetchTorrent(link)
+	if err != nil {
+		logger.Error(err, "Failed to download torrent")
+		http.Error(w, `{"error": "failed to download torrent"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := bencode.ParseTorrent(torrentData); err != nil {
+		logger.Warn("Downloaded file from %s is not a torrent: %v", req.DownloadURL, err)
+		writeJSONError(w, fmt.Sprintf("downloaded file is not a valid torrent: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	torrentBase64 := base64.StdEncoding.EncodeToString(torrentData)
+	app.addTorrentAndRespond(userID, w, transmission.AddTorrentArg{Metainfo: torrentBase64}, req.Category)
+}
+
+// fetchTorrent downloads a resolved link, through the authenticated Rutracker
+// client for Rutracker topics and over plain HTTP for trusted hosts.
+func (app *App) fetchTorrent(link torrentlink.Link) ([]byte, error) {
+	if link.Kind == torrentlink.KindDirect {
+		return torrentlink.Fetch(link, app.config.TrustedTorrentHosts)
+	}
+
+	start := time.Now()
 	client, err := rutracker.NewAuthenticatedRutrackerClient(
 		app.config.RutrackerUsername,
 		app.config.RutrackerPassword,
 		rutracker.WithTimeout(30*time.Second),
 		rutracker.WithIPv6(),
 	)
+	metrics.ObserveRutracker(metrics.RutrackerLogin, start, err)
 	if err != nil {
-		logger.Error(err, "Failed to authenticate with rutracker")
-		http.Error(w, `{"error": "failed to authenticate with rutracker"}`, http.StatusInternalServerError)
+		return nil, fmt.Errorf("failed to authenticate with rutracker: %w", err)
+	}
+	start = time.Now()
+	data, err := client.DownloadTorrent(link.URL)
+	metrics.ObserveRutracker(metrics.RutrackerDownload, start, err)
+	return data, err
+}
+
+func (app *App) handleAddMagnet(userID int64, w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB
+	var req MagnetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Warn("Failed to decode request body: %v", err)
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
 		return
 	}
 
-	torrentData, err := client.DownloadTorrent(req.DownloadURL)
+	link, err := magnet.Parse(req.Magnet)
 	if err != nil {
-		logger.Error(err, "Failed to download torrent")
-		http.Error(w, `{"error": "failed to download torrent"}`, http.StatusInternalServerError)
+		logger.Warn("Invalid magnet from user %d: %v", userID, err)
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	torrentBase64 := base64.StdEncoding.EncodeToString(torrentData)
-	downloadPath := fmt.Sprintf("%s/%s/", app.config.DownloadPath, req.Category)
-	torrent, err := app.transmissionClient.AddTorrent(transmission.AddTorrentArg{
-		Metainfo:    torrentBase64,
-		DownloadDir: downloadPath,
-	})
+	if req.Category == "" {
+		http.Error(w, `{"error": "category is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !slices.Contains(validCategories, req.Category) {
+		http.Error(w, `{"error": "invalid category"}`, http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Magnet request from user %d: %s [%s]", userID, link.InfoHash, req.Category)
+	app.addTorrentAndRespond(userID, w, transmission.AddTorrentArg{Filename: link.URI}, req.Category)
+}
+
+// addTorrentAndRespond adds a torrent into the category directory, labels it with
+// the user and category, and writes the JSON response.
+func (app *App) addTorrentAndRespond(userID int64, w http.ResponseWriter, arg transmission.AddTorrentArg, category string) {
+	arg.DownloadDir = fmt.Sprintf("%s/%s/", app.config.DownloadPath, category)
+	torrent, err := app.transmissionClient.AddTorrent(arg)
 	if err != nil {
 		logger.Error(err, "Failed to add torrent to Transmission")
 		http.Error(w, `{"error": "failed to add torrent"}`, http.StatusInternalServerError)
 		return
 	}
 
-	labels := []string{fmt.Sprintf("%d", userID), req.Category}
+	labels := []string{fmt.Sprintf("%d", userID), category}
 	err = torrent.Set(transmission.SetTorrentArg{
 		Labels: labels,
 	})
@@ -347,7 +384,7 @@ func (app *App) handleDownloadTorrent(userID int64, w http.ResponseWriter, r *ht
 		return
 	}
 
-	logger.Info("Added torrent %d: %s [%s] for user %d", torrent.ID, torrent.Name, req.Category, userID)
+	logger.Info("Added torrent %d: %s [%s] for user %d", torrent.ID, torrent.Name, category, userID)
 	if err := json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
 		"torrent": map[string]any{
@@ -366,12 +403,20 @@ func (app *App) handleSearch(userID int64, w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	parsedQuery, err := searchquery.Parse(query)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
 	client, err := rutracker.NewAuthenticatedRutrackerClient(
 		app.config.RutrackerUsername,
 		app.config.RutrackerPassword,
 		rutracker.WithTimeout(30*time.Second),
 		rutracker.WithIPv6(),
 	)
+	metrics.ObserveRutracker(metrics.RutrackerLogin, start, err)
 
 	if err != nil {
 		logger.Error(err, "Failed to authenticate with rutracker")
@@ -379,17 +424,17 @@ func (app *App) handleSearch(userID int64, w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	items, err := client.Find(query)
+	start = time.Now()
+	items, err := client.Find(parsedQuery.Text)
+	metrics.ObserveRutracker(metrics.RutrackerSearch, start, err)
 	if err != nil {
 		logger.Error(err, "Failed to search rutracker")
 		http.Error(w, `{"error": "search failed"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Sort by seeders descending
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Seeders > items[j].Seeders
-	})
+	// Apply filters and sorting (seeders descending unless the query says otherwise)
+	items = parsedQuery.Apply(items)
 
 	// Limit to 20 results
 	if len(items) > 20 {
@@ -439,7 +484,9 @@ func (app *App) handleUnifiedItems(userID int64, w http.ResponseWriter, r *http.
 
 	start = time.Now()
 	for _, cat := range categories {
+		scanStart := time.Now()
 		items, err := scanner.ScanCategory(cat)
+		scanDuration.WithLabelValues(cat).Observe(time.Since(scanStart).Seconds())
 		if err != nil {
 			logger.Error(err, "Failed to scan filesystem category %s", cat)
 			continue
@@ -450,7 +497,9 @@ func (app *App) handleUnifiedItems(userID int64, w http.ResponseWriter, r *http.
 	}
 	logger.Debug("Scan filesystem took %s", time.Since(start))
 
+	scanStart := time.Now()
 	incompleteItems, err := scanner.ScanIncomplete()
+	scanDuration.WithLabelValues("incomplete").Observe(time.Since(scanStart).Seconds())
 	if err != nil {
 		logger.Error(err, "Failed to scan incomplete directory")
 	}
@@ -632,6 +681,15 @@ func (app *App) handleRemoveItemData(userID int64, w http.ResponseWriter, catego
 	}
 }
 
+// writeJSONError writes an {"error": message} response with the given status code.
+func writeJSONError(w http.ResponseWriter, message string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		logger.Error(err, "Failed to encode error response")
+	}
+}
+
 func validateRequest(r *http.Request, w http.ResponseWriter, botToken string) (*initData, bool) {
 	initData := r.Header.Get("X-Telegram-Init-Data")
 	if initData == "" {
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/odwrtw/transmission"
+)
+
+// fakeTransmission records RPC requests and answers torrent-add and torrent-set.
+func fakeTransmission(t *testing.T, requests *[]map[string]any) *transmission.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode rpc request: %v", err)
+		}
+		*requests = append(*requests, req)
+		switch req["method"] {
+		case "torrent-add":
+			w.Write([]byte(`{"arguments":{"torrent-added":{"id":7,"name":"Movie","hashString":"c12f"}},"result":"success"}`))
+		default:
+			w.Write([]byte(`{"arguments":{},"result":"success"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := transmission.New(transmission.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("failed to create transmission client: %v", err)
+	}
+	return client
+}
+
+func TestHandleAddMagnet(t *testing.T) {
+	var requests []map[string]any
+	app := &App{
+		config:             Config{DownloadPath: "/downloads"},
+		transmissionClient: fakeTransmission(t, &requests),
+	}
+
+	body := `{"magnet":"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Movie","category":"movies"}`
+	rec := httptest.NewRecorder()
+	app.handleAddMagnet(111, rec, httptest.NewRequest(http.MethodPost, "/api/torrents/magnet", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected torrent-add and torrent-set, got %v", requests)
+	}
+	addArgs := requests[0]["arguments"].(map[string]any)
+	if !strings.HasPrefix(addArgs["filename"].(string), "magnet:?") || addArgs["download-dir"] != "/downloads/movies/" {
+		t.Errorf("unexpected torrent-add arguments: %v", addArgs)
+	}
+	setArgs := requests[1]["arguments"].(map[string]any)
+	labels := setArgs["labels"].([]any)
+	if len(labels) != 2 || labels[0] != "111" || labels[1] != "movies" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+}
+
+func TestHandleAddMagnet_Invalid(t *testing.T) {
+	var requests []map[string]any
+	app := &App{transmissionClient: fakeTransmission(t, &requests)}
+
+	for _, body := range []string{
+		`{"magnet":"https://example.org/x.torrent","category":"movies"}`,
+		`{"magnet":"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a","category":"games"}`,
+		`not json`,
+	} {
+		rec := httptest.NewRecorder()
+		app.handleAddMagnet(111, rec, httptest.NewRequest(http.MethodPost, "/api/torrents/magnet", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	if len(requests) != 0 {
+		t.Errorf("expected no RPC calls for invalid requests, got %v", requests)
+	}
+}
+
+const testTorrent = "d4:infod6:lengthi1024e4:name8:show.mkv12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"
+
+func TestHandleDownloadTorrent_TrustedHost(t *testing.T) {
+	torrentSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTorrent))
+	}))
+	defer torrentSrv.Close()
+
+	var requests []map[string]any
+	app := &App{
+		config:             Config{DownloadPath: "/downloads", TrustedTorrentHosts: []string{"127.0.0.1"}},
+		transmissionClient: fakeTransmission(t, &requests),
+	}
+
+	body := `{"downloadUrl":"` + torrentSrv.URL + `/show.torrent","category":"shows"}`
+	rec := httptest.NewRecorder()
+	app.handleDownloadTorrent(111, rec, httptest.NewRequest(http.MethodPost, "/api/torrents/download", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	addArgs := requests[0]["arguments"].(map[string]any)
+	if addArgs["metainfo"] == "" || addArgs["download-dir"] != "/downloads/shows/" {
+		t.Errorf("unexpected torrent-add arguments: %v", addArgs)
+	}
+}
+
+func TestHandleDownloadTorrent_UntrustedHost(t *testing.T) {
+	var requests []map[string]any
+	app := &App{transmissionClient: fakeTransmission(t, &requests)}
+
+	body := `{"downloadUrl":"https://example.com/show.torrent","category":"shows"}`
+	rec := httptest.NewRecorder()
+	app.handleDownloadTorrent(111, rec, httptest.NewRequest(http.MethodPost, "/api/torrents/download", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+	if len(requests) != 0 {
+		t.Errorf("expected no RPC calls, got %v", requests)
+	}
+}
+
+func TestHandleDownloadTorrent_NotATorrent(t *testing.T) {
+	pageSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>login required</html>"))
+	}))
+	defer pageSrv.Close()
+
+	var requests []map[string]any
+	app := &App{
+		config:             Config{TrustedTorrentHosts: []string{"127.0.0.1"}},
+		transmissionClient: fakeTransmission(t, &requests),
+	}
+
+	body := `{"downloadUrl":"` + pageSrv.URL + `/show.torrent","category":"shows"}`
+	rec := httptest.NewRecorder()
+	app.handleDownloadTorrent(111, rec, httptest.NewRequest(http.MethodPost, "/api/torrents/download", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", rec.Code)
+	}
+	if len(requests) != 0 {
+		t.Errorf("expected no RPC calls, got %v", requests)
+	}
+}
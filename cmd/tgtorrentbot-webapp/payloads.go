@@ -18,6 +18,11 @@ type DownloadRequest struct {
 	Category    string `json:"category"`
 }
 
+type MagnetRequest struct {
+	Magnet   string `json:"magnet"`
+	Category string `json:"category"`
+}
+
 type SearchResult struct {
 	Title       string `json:"title"`
 	Size        string `json:"size"`
@@ -39,4 +44,5 @@ type UnifiedItem struct {
 	Eta              *int     `json:"eta,omitempty"`
 	PeersConnected   *int     `json:"peersConnected,omitempty"`
 	PeersSendingToUs *int     `json:"peersSendingToUs,omitempty"`
+	IsExtracted      bool     `json:"isExtracted,omitempty"`
 }
@@ -4,6 +4,8 @@ import (
 	"slices"
 	"sort"
 	"strings"
+
+	"github.com/minya/tgtorrentbot/jellyfin"
 )
 
 // normalizedKey returns a lowercase key used to match items across sources.
@@ -13,7 +15,7 @@ func normalizedKey(name, category string) string {
 
 // mergeItems combines items from torrents, filesystem, and Jellyfin into a
 // unified list. Items are matched by normalized name + category.
-func mergeItems(torrents []TorrentInfo, fsItems map[string][]FsItem, incompleteItems []FsItem, jellyfinItems []JellyfinItem) []UnifiedItem {
+func mergeItems(torrents []TorrentInfo, fsItems map[string][]FsItem, incompleteItems []FsItem, jellyfinItems []jellyfin.Item) []UnifiedItem {
 	type entry struct {
 		item  UnifiedItem
 		order int // insertion order for stable sort
@@ -69,6 +71,9 @@ func mergeItems(torrents []TorrentInfo, fsItems map[string][]FsItem, incompleteI
 			if fi.Size > e.item.TotalSize {
 				e.item.TotalSize = fi.Size
 			}
+			if fi.IsExtracted {
+				e.item.IsExtracted = true
+			}
 		}
 	}
 
@@ -3,6 +3,8 @@ package main
 import (
 	"slices"
 	"testing"
+
+	"github.com/minya/tgtorrentbot/jellyfin"
 )
 
 func TestMergeItems_AllThreeSources(t *testing.T) {
@@ -12,8 +14,8 @@ func TestMergeItems_AllThreeSources(t *testing.T) {
 	fsItems := map[string][]FsItem{
 		"movies": {{Name: "MyMovie", Size: 1000}},
 	}
-	jellyfinItems := []JellyfinItem{
-		{Name: "MyMovie", Category: "movies", JellyfinID: "jf-1"},
+	jellyfinItems := []jellyfin.Item{
+		{Name: "MyMovie", Category: "movies", ID: "jf-1"},
 	}
 
 	result := mergeItems(torrents, fsItems, nil, jellyfinItems)
@@ -86,9 +88,20 @@ func TestMergeItems_OnlyFilesystem(t *testing.T) {
 	}
 }
 
+func TestMergeItems_Extracted(t *testing.T) {
+	torrents := []TorrentInfo{{ID: 1, Name: "Album1", Category: "music", PercentDone: 1}}
+	fsItems := map[string][]FsItem{
+		"music": {{Name: "Album1", Size: 500, IsExtracted: true}},
+	}
+	result := mergeItems(torrents, fsItems, nil, nil)
+	if len(result) != 1 || !result[0].IsExtracted {
+		t.Fatalf("expected one extracted item, got %+v", result)
+	}
+}
+
 func TestMergeItems_OnlyJellyfin(t *testing.T) {
-	jellyfinItems := []JellyfinItem{
-		{Name: "JellyMovie", Category: "movies", JellyfinID: "jf-10"},
+	jellyfinItems := []jellyfin.Item{
+		{Name: "JellyMovie", Category: "movies", ID: "jf-10"},
 	}
 	result := mergeItems(nil, nil, nil, jellyfinItems)
 	if len(result) != 1 {
@@ -184,8 +197,8 @@ func TestMergeItems_MultipleItems(t *testing.T) {
 	fsItems := map[string][]FsItem{
 		"movies": {{Name: "Movie1", Size: 1000}, {Name: "Movie3", Size: 3000}},
 	}
-	jellyfinItems := []JellyfinItem{
-		{Name: "Movie2", Category: "movies", JellyfinID: "jf-2"},
+	jellyfinItems := []jellyfin.Item{
+		{Name: "Movie2", Category: "movies", ID: "jf-2"},
 	}
 	result := mergeItems(torrents, fsItems, nil, jellyfinItems)
 	if len(result) != 3 {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/completionhooks"
+	"github.com/minya/tgtorrentbot/preferences"
+	"github.com/odwrtw/transmission"
+)
+
+// completionEvent describes a completed torrent to its hooks; the owner is 0
+// for torrents added outside the bot.
+func completionEvent(torrent *transmission.Torrent) completionhooks.Event {
+	event := completionhooks.Event{
+		ID:          torrent.ID,
+		Name:        torrent.Name,
+		Hash:        torrent.HashString,
+		Category:    getTorrentCategory(torrent),
+		DownloadDir: torrent.DownloadDir,
+	}
+	if chatID, err := getTorrentChatID(torrent); err == nil {
+		event.Owner = chatID
+	}
+	return event
+}
+
+// runCompletionHooks runs the torrent's hooks in the background, so slow hooks
+// don't hold up polling, and reports failures to the owner.
+func runCompletionHooks(n *notifier, runner *completionhooks.Runner, torrent *transmission.Torrent) {
+	event := completionEvent(torrent)
+	if !runner.Has(event.Category) {
+		return
+	}
+
+	go func() {
+		logger.Info("[UpdatesChecker] Running completion hooks for %s", event.Name)
+		failures := runner.Run(context.Background(), event)
+		for _, failure := range failures {
+			logger.Warn("[UpdatesChecker] Hook %s failed for %s: %v", failure.Hook, event.Name, failure.Err)
+		}
+		if len(failures) == 0 || event.Owner == 0 {
+			return
+		}
+		if _, err := n.send(event.Owner, preferences.EventHookFailed, commands.FormatHookFailures(torrent, failures), nil); err != nil {
+			logger.Error(err, "[UpdatesChecker] Error reporting hook failures for %s", event.Name)
+		}
+	}()
+}
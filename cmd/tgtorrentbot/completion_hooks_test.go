@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/minya/tgtorrentbot/completionhooks"
+	"github.com/odwrtw/transmission"
+)
+
+func TestCompletionEvent(t *testing.T) {
+	torrent := &transmission.Torrent{
+		ID:          3,
+		Name:        "Album",
+		HashString:  "abc",
+		DownloadDir: "/downloads/music",
+		Labels:      []string{"42", "music"},
+	}
+	want := completionhooks.Event{ID: 3, Name: "Album", Hash: "abc", Category: "music", DownloadDir: "/downloads/music", Owner: 42}
+	if got := completionEvent(torrent); got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Torrents added outside the bot have no owner or category
+	torrent.Labels = nil
+	if got := completionEvent(torrent); got.Owner != 0 || got.Category != "" {
+		t.Errorf("expected no owner or category, got %#v", got)
+	}
+}
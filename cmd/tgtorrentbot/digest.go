@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/preferences"
+	"github.com/odwrtw/transmission"
+)
+
+// defaultDigestTime is used when no digest time is configured.
+const defaultDigestTime = "08:00"
+
+// digestSchedule sends the daily digest to subscribed users at a local time of day.
+type digestSchedule struct {
+	hour         int
+	minute       int
+	location     *time.Location
+	preferences  *preferences.Store
+	users        []int64
+	downloadPath string
+}
+
+// parseDigestTime parses a "HH:MM" time of day.
+func parseDigestTime(s string) (hour int, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid digest time %q, expected HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// lastScheduled returns the most recent digest time at or before now.
+func (d *digestSchedule) lastScheduled(now time.Time) time.Time {
+	local := now.In(d.location)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), d.hour, d.minute, 0, 0, d.location)
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return scheduled
+}
+
+// due reports whether a digest is owed. On a fresh state the current slot is
+// marked as sent so that a restart does not send an unexpected digest.
+func (d *digestSchedule) due(state *notifierState, now time.Time) bool {
+	scheduled := d.lastScheduled(now)
+	last := state.lastDigest()
+	if last.IsZero() {
+		state.setLastDigest(scheduled)
+		return false
+	}
+	return last.Before(scheduled)
+}
+
+// sendDigests sends the digest to every subscribed user, using the torrents of
+// the current check cycle.
+func (d *digestSchedule) sendDigests(n *notifier, torrents transmission.TorrentMap, state *notifierState, now time.Time, stallTimeout time.Duration) {
+	problems := state.problems(torrents, now, stallTimeout)
+	list := make([]*transmission.Torrent, 0, len(torrents))
+	for _, torrent := range torrents {
+		list = append(list, torrent)
+	}
+
+	var usage []commands.CategoryUsage
+	for _, userID := range d.users {
+		if !d.preferences.Get(userID).Digest {
+			continue
+		}
+		if usage == nil {
+			usage = commands.CategoryDiskUsage(d.downloadPath)
+		}
+
+		report := commands.BuildDigestReport(list, userID, now, problems, usage)
+		if _, err := n.send(userID, preferences.EventDigest, commands.FormatDigest(report), nil); err != nil {
+			logger.Error(err, "[UpdatesChecker] Error sending digest to %d", userID)
+		}
+	}
+
+	state.setLastDigest(now)
+	if err := state.save(); err != nil {
+		logger.Error(err, "[UpdatesChecker] Error saving notifier state")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDigestTime(t *testing.T) {
+	if hour, minute, err := parseDigestTime("07:30"); err != nil || hour != 7 || minute != 30 {
+		t.Errorf("parseDigestTime() = %d, %d, %v", hour, minute, err)
+	}
+	for _, s := range []string{"7", "25:00", "morning"} {
+		if _, _, err := parseDigestTime(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestDigestScheduleDue(t *testing.T) {
+	loc := time.FixedZone("test", 3*3600)
+	digest := &digestSchedule{hour: 8, minute: 0, location: loc}
+	state := &notifierState{Torrents: map[string]torrentProgress{}}
+
+	morning := time.Date(2026, 3, 10, 7, 0, 0, 0, loc)
+	if digest.due(state, morning) {
+		t.Error("expected a fresh state not to owe a digest")
+	}
+	if want := time.Date(2026, 3, 9, 8, 0, 0, 0, loc); !state.LastDigest.Equal(want) {
+		t.Errorf("LastDigest = %v, want %v", state.LastDigest, want)
+	}
+
+	if !digest.due(state, morning.Add(90*time.Minute)) {
+		t.Error("expected a digest to be due after 08:00")
+	}
+	state.setLastDigest(morning.Add(90 * time.Minute))
+	if digest.due(state, morning.Add(10*time.Hour)) {
+		t.Error("expected only one digest per day")
+	}
+	if !digest.due(state, morning.Add(25*time.Hour)) {
+		t.Error("expected the next day's digest to be due")
+	}
+}
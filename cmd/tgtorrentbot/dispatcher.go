@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+)
+
+const (
+	// updateWorkers is how many updates are handled at once.
+	updateWorkers = 8
+	// updateQueueSize is how many updates may wait for each worker.
+	updateQueueSize = 32
+	// seenUpdates is how many recent update IDs are remembered to drop duplicates.
+	seenUpdates = 1024
+)
+
+var errDispatcherClosed = errors.New("update dispatcher is shutting down")
+
+// dispatcher hands updates to a fixed set of workers. All updates of a chat go
+// to the same worker, so they are handled in the order they arrived, and an
+// update delivered again by Telegram is dropped.
+type dispatcher struct {
+	handleUpdate func(*telegram.Update) error
+	queues       []chan *telegram.Update
+	workers      sync.WaitGroup
+
+	// mu is held for reading while queueing and for writing to close the queues.
+	mu     sync.RWMutex
+	closed bool
+
+	seenMu sync.Mutex
+	// seen holds the last seenUpdates IDs, in order in recent.
+	seen   map[int64]bool
+	recent []int64
+}
+
+func newDispatcher(workers int, queueSize int, handleUpdate func(*telegram.Update) error) *dispatcher {
+	d := &dispatcher{
+		handleUpdate: handleUpdate,
+		queues:       make([]chan *telegram.Update, workers),
+		seen:         map[int64]bool{},
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan *telegram.Update, queueSize)
+		d.workers.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+func (d *dispatcher) work(queue chan *telegram.Update) {
+	defer d.workers.Done()
+	for upd := range queue {
+		if err := d.handleUpdate(upd); err != nil {
+			logger.Error(err, "Failed to handle update %d", upd.UpdateId)
+		}
+	}
+}
+
+// dispatch queues the update on its chat's worker, waiting for room until ctx
+// is done. Duplicates are accepted and dropped.
+func (d *dispatcher) dispatch(ctx context.Context, upd *telegram.Update) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return errDispatcherClosed
+	}
+	if !d.remember(upd.UpdateId) {
+		logger.Info("Dropping duplicate update %d", upd.UpdateId)
+		return nil
+	}
+
+	queue := d.queues[updateChatID(upd)%int64(len(d.queues))]
+	select {
+	case queue <- upd:
+		return nil
+	case <-ctx.Done():
+		// Not queued, so a redelivery must not count as a duplicate
+		d.forget(upd.UpdateId)
+		return ctx.Err()
+	}
+}
+
+// remember records the update ID and reports whether it is new.
+func (d *dispatcher) remember(id int64) bool {
+	d.seenMu.Lock()
+	defer d.seenMu.Unlock()
+	if d.seen[id] {
+		return false
+	}
+	d.seen[id] = true
+	d.recent = append(d.recent, id)
+	if len(d.recent) > seenUpdates {
+		delete(d.seen, d.recent[0])
+		d.recent = d.recent[1:]
+	}
+	return true
+}
+
+func (d *dispatcher) forget(id int64) {
+	d.seenMu.Lock()
+	defer d.seenMu.Unlock()
+	delete(d.seen, id)
+}
+
+// shutdown stops accepting updates and waits for the queued ones to be
+// handled, or for ctx to be done.
+func (d *dispatcher) shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// updateChatID is the chat an update belongs to, or the sender for updates
+// without a chat; never negative, so it can pick a worker.
+func updateChatID(upd *telegram.Update) int64 {
+	var id int64
+	switch {
+	case upd.Message != nil:
+		id = upd.Message.Chat.Id
+	case upd.CallbackQuery != nil && upd.CallbackQuery.Message != nil:
+		id = upd.CallbackQuery.Message.Chat.Id
+	case upd.CallbackQuery != nil && upd.CallbackQuery.From != nil:
+		id = upd.CallbackQuery.From.Id
+	}
+	if id < 0 {
+		// Group chat IDs are negative
+		id = -id
+	}
+	return id
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+)
+
+func chatUpdate(id int64, chatID int64) *telegram.Update {
+	return &telegram.Update{UpdateId: id, Message: &telegram.Message{Chat: telegram.Chat{Id: chatID}}}
+}
+
+func TestDispatcher_OrderPerChat(t *testing.T) {
+	var mu sync.Mutex
+	handled := map[int64][]int64{}
+	d := newDispatcher(4, 100, func(upd *telegram.Update) error {
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := upd.Message.Chat.Id
+		handled[chatID] = append(handled[chatID], upd.UpdateId)
+		return nil
+	})
+
+	var id int64
+	for range 50 {
+		for _, chatID := range []int64{1, 2, -1003} {
+			id++
+			if err := d.dispatch(context.Background(), chatUpdate(id, chatID)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	if err := d.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for chatID, ids := range handled {
+		if len(ids) != 50 {
+			t.Errorf("chat %d: expected 50 updates, got %d", chatID, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("chat %d: updates handled out of order: %v", chatID, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcher_DropsDuplicates(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int64
+	d := newDispatcher(2, 10, func(upd *telegram.Update) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, upd.UpdateId)
+		return nil
+	})
+
+	for _, id := range []int64{5, 6, 5, 6, 7} {
+		if err := d.dispatch(context.Background(), chatUpdate(id, 1)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	d.shutdown(context.Background())
+
+	if len(handled) != 3 {
+		t.Errorf("expected updates 5, 6 and 7 to be handled once, got %v", handled)
+	}
+}
+
+func TestDispatcher_FullQueue(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int64
+	d := newDispatcher(1, 1, func(upd *telegram.Update) error {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, upd.UpdateId)
+		return nil
+	})
+
+	// One update is being handled and one waits in the queue
+	d.dispatch(context.Background(), chatUpdate(1, 1))
+	<-started
+	d.dispatch(context.Background(), chatUpdate(2, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.dispatch(ctx, chatUpdate(3, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a full queue to time out, got %v", err)
+	}
+
+	// An update that was not queued is accepted when delivered again
+	close(release)
+	if err := d.dispatch(context.Background(), chatUpdate(3, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.shutdown(context.Background())
+	if len(handled) != 3 {
+		t.Errorf("expected updates 1, 2 and 3 to be handled, got %v", handled)
+	}
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	d := newDispatcher(1, 10, func(upd *telegram.Update) error {
+		<-release
+		return nil
+	})
+	d.dispatch(context.Background(), chatUpdate(1, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to pass while an update is handled, got %v", err)
+	}
+	if err := d.dispatch(context.Background(), chatUpdate(2, 1)); !errors.Is(err, errDispatcherClosed) {
+		t.Errorf("expected updates to be refused after shutdown, got %v", err)
+	}
+
+	close(release)
+	if err := d.shutdown(context.Background()); err != nil {
+		t.Errorf("expected the update in progress to finish, got %v", err)
+	}
+}
+
+func TestUpdateChatID(t *testing.T) {
+	callback := &telegram.Update{CallbackQuery: &telegram.CallbackQuery{From: &telegram.User{Id: 42}}}
+	if got := updateChatID(callback); got != 42 {
+		t.Errorf("got %d, want the sender 42", got)
+	}
+	if got := updateChatID(chatUpdate(1, -100)); got != 100 {
+		t.Errorf("got %d, want 100 for a group chat", got)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/odwrtw/transmission"
+)
+
+// extractingNote ends a completion notice while its archives are extracted.
+const extractingNote = "📦 Extracting archives…"
+
+// extractor extracts completed torrents off the checker loop, one at a time so
+// large releases don't compete for the disk, and then completes their notices.
+type extractor struct {
+	mu sync.Mutex
+	// extract returns the report for the notice, empty when there were no archives.
+	extract func(torrent *transmission.Torrent) string
+	// running counts the extractions not yet finished, for tests.
+	running sync.WaitGroup
+}
+
+func newExtractor() *extractor {
+	return &extractor{extract: extractCompleted}
+}
+
+// run extracts the torrent in the background, replaces the extracting note of
+// its notice with the report and then calls done, which runs what needs the
+// extracted files.
+func (x *extractor) run(n *notifier, torrent *transmission.Torrent, notice completionNotice, done func(completionNotice)) {
+	x.running.Add(1)
+	go func() {
+		defer x.running.Done()
+		x.mu.Lock()
+		report := x.extract(torrent)
+		x.mu.Unlock()
+
+		notice.text = commands.FormatTorrentCompleted(torrent)
+		if report != "" {
+			notice.text += "\n" + report
+		}
+		if notice.messageID != 0 {
+			if err := n.edit(notice.chatID, notice.messageID, notice.text, notice.keyboard); err != nil {
+				logger.Warn("[UpdatesChecker] Error adding the extraction report to %s: %v", torrent.Name, err)
+			}
+		}
+		done(notice)
+	}()
+}
+
+// extractCompleted extracts the archives of a completed torrent and returns
+// the report for its completion notice, empty when it had no archives.
+func extractCompleted(torrent *transmission.Torrent) string {
+	path := filepath.Join(torrent.DownloadDir, torrent.Name)
+	logger.Info("[UpdatesChecker] Extracting archives of %s", torrent.Name)
+
+	result, err := extract.Item(path)
+	if err != nil {
+		logger.Error(err, "[UpdatesChecker] Error extracting archives of %s", torrent.Name)
+		return commands.FormatExtractionError(err)
+	}
+	for _, failure := range result.Failed {
+		logger.Warn("[UpdatesChecker] Error extracting %s: %s", failure.Archive, failure.Error)
+	}
+	return commands.FormatExtraction(result)
+}
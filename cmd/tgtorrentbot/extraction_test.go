@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/odwrtw/transmission"
+)
+
+func TestExtractorRunsInBackground(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	x := &extractor{extract: func(torrent *transmission.Torrent) string {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return "📦 Extracted 1 archive(s)"
+	}}
+
+	var doneMu sync.Mutex
+	var texts []string
+	done := func(notice completionNotice) {
+		doneMu.Lock()
+		defer doneMu.Unlock()
+		texts = append(texts, notice.text)
+	}
+	for _, name := range []string{"First", "Second"} {
+		torrent := &transmission.Torrent{Name: name, Labels: []string{"1", "movies"}}
+		// No message was sent, so nothing is edited
+		x.run(nil, torrent, completionNotice{text: "Completed: " + name + "\n" + extractingNote}, done)
+	}
+
+	// run returned while both extractions wait
+	close(release)
+	x.running.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("expected one extraction at a time, got %d", maxActive)
+	}
+	if len(texts) != 2 {
+		t.Fatalf("expected both notices to be completed, got %v", texts)
+	}
+	for _, text := range texts {
+		if strings.Contains(text, extractingNote) || !strings.HasSuffix(text, "\n📦 Extracted 1 archive(s)") {
+			t.Errorf("expected the extracting note to be replaced by the report, got %q", text)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"slices"
+	"time"
 
 	"github.com/minya/logger"
 	"github.com/minya/telegram"
@@ -24,9 +25,24 @@ func NewUpdatesHandler(env environment.Env, notifyFunc func()) *UpdatesHandler {
 			&commands.ListCommandFactory{Env: env},
 			&commands.ListPageCommandFactory{Env: env},
 			&commands.RemoveTorrentCommandFactory{Env: env},
+			&commands.RemoveConfirmCommandFactory{Env: env},
+			&commands.RemovePickerCommandFactory{Env: env},
+			&commands.InfoCommandFactory{Env: env},
+			&commands.TorrentActionCommandFactory{Env: env},
+			&commands.TorrentControlCommandFactory{Env: env},
+			&commands.DigestCommandFactory{Env: env},
+			&commands.SettingsCommandFactory{Env: env},
+			&SendToChatCommandFactory{Env: env},
+			&commands.MagnetCommandFactory{Env: env},                     // Must come before SearchCommandFactory
+			&commands.TorrentLinkCommandFactory{Env: env},                // Must come before SearchCommandFactory
 			&commands.SearchCommandFactory{Env: env},
+			&commands.SearchPageCommandFactory{Env: env},
+			&commands.SearchDownloadCommandFactory{Env: env},
 			&commands.DownloadWithCategoryCommandFactory{Env: env},       // Must come before DownloadCommandFactory
 			&commands.DownloadFileWithCategoryCommandFactory{Env: env},   // Must come before DownloadByFileCommandFactory
+			&commands.DownloadMagnetWithCategoryCommandFactory{Env: env},
+			&commands.DownloadURLWithCategoryCommandFactory{Env: env},
+			&commands.DownloadBatchWithCategoryCommandFactory{Env: env},
 			&commands.DownloadCommandFactory{Env: env},
 			&commands.DownloadByFileCommandFactory{Env: env},
 		},
@@ -59,13 +75,20 @@ func (handler *UpdatesHandler) HandleUpdate(upd *telegram.Update) error {
 	for _, factory := range handler.commandsList {
 		accepts, cmd := factory.Accepts(upd)
 		if accepts {
+			name := commandName(cmd)
+			updatesHandled.WithLabelValues(name).Inc()
+			start := time.Now()
 			handleErr := cmd.Handle(upd)
+			commandDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
 			if handleErr == nil {
 				handler.notify()
+			} else {
+				commandErrors.WithLabelValues(name).Inc()
 			}
 			return handleErr
 		}
 	}
+	updatesHandled.WithLabelValues(noCommand).Inc()
 
 	var replyChatID int64
 	switch {
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/jellyfin"
+	"github.com/odwrtw/transmission"
+)
+
+const (
+	// jellyfinPollInterval is how often Jellyfin is asked whether it indexed an item.
+	jellyfinPollInterval = 30 * time.Second
+	// jellyfinIndexTimeout is how long to wait for an item before giving up on its link.
+	jellyfinIndexTimeout = 15 * time.Minute
+)
+
+// jellyfinAnnouncer makes Jellyfin scan completed downloads and links the
+// completion notice to the item once it is indexed.
+type jellyfinAnnouncer struct {
+	client *jellyfin.Client
+	// publicURL is the Jellyfin address users open; no link is added without it.
+	publicURL    string
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+func newJellyfinAnnouncer(client *jellyfin.Client, publicURL string) *jellyfinAnnouncer {
+	if !client.Configured() {
+		return nil
+	}
+	return &jellyfinAnnouncer{
+		client:       client,
+		publicURL:    publicURL,
+		pollInterval: jellyfinPollInterval,
+		timeout:      jellyfinIndexTimeout,
+	}
+}
+
+// announce refreshes the torrent's path in Jellyfin and, in the background,
+// waits for the item to be indexed to add a "Watch in Jellyfin" button to the
+// completion notice.
+func (j *jellyfinAnnouncer) announce(n *notifier, torrent *transmission.Torrent, notice completionNotice) {
+	if j == nil {
+		return
+	}
+	category := getTorrentCategory(torrent)
+	if category == "" {
+		return
+	}
+
+	go func() {
+		itemPath := j.client.ItemPath(category, torrent.Name)
+		if err := j.client.RefreshPath(itemPath); err != nil {
+			logger.Warn("[UpdatesChecker] Error refreshing %s in Jellyfin: %v", itemPath, err)
+			return
+		}
+		logger.Info("[UpdatesChecker] Requested Jellyfin refresh of %s", itemPath)
+
+		if j.publicURL == "" || notice.messageID == 0 {
+			return
+		}
+		item, ok := j.waitForItem(category, torrent.Name)
+		if !ok {
+			logger.Info("[UpdatesChecker] %s did not show up in Jellyfin within %s", torrent.Name, j.timeout)
+			return
+		}
+
+		keyboard := watchInJellyfinKeyboard(notice.keyboard, jellyfin.WatchURL(j.publicURL, item.ID))
+		if err := n.edit(notice.chatID, notice.messageID, notice.text, &keyboard); err != nil {
+			logger.Warn("[UpdatesChecker] Error adding Jellyfin link to %s: %v", torrent.Name, err)
+		}
+	}()
+}
+
+func (j *jellyfinAnnouncer) waitForItem(category, name string) (jellyfin.Item, bool) {
+	deadline := time.Now().Add(j.timeout)
+	for {
+		time.Sleep(j.pollInterval)
+		item, ok, err := j.client.FindItem(category, name)
+		if err != nil {
+			logger.Warn("[UpdatesChecker] Error looking up %s in Jellyfin: %v", name, err)
+		}
+		if ok {
+			return item, true
+		}
+		if time.Now().After(deadline) {
+			return jellyfin.Item{}, false
+		}
+	}
+}
+
+// watchInJellyfinKeyboard adds the "Watch in Jellyfin" button below the
+// notice's own buttons.
+func watchInJellyfinKeyboard(keyboard *telegram.InlineKeyboardMarkup, url string) telegram.InlineKeyboardMarkup {
+	var rows [][]telegram.InlineKeyboardButton
+	if keyboard != nil {
+		rows = append(rows, keyboard.InlineKeyboard...)
+	}
+	rows = append(rows, []telegram.InlineKeyboardButton{{Text: "▶ Watch in Jellyfin", Url: url}})
+	return telegram.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
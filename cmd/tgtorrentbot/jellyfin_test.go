@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/jellyfin"
+)
+
+func TestNewJellyfinAnnouncerNotConfigured(t *testing.T) {
+	if announcer := newJellyfinAnnouncer(jellyfin.New("", "", ""), "https://media.example.org"); announcer != nil {
+		t.Errorf("expected no announcer without Jellyfin, got %+v", announcer)
+	}
+	if announcer := newJellyfinAnnouncer(jellyfin.New("http://jellyfin:8096", "key", ""), ""); announcer == nil {
+		t.Error("expected an announcer when Jellyfin is configured")
+	}
+}
+
+func TestWaitForItem(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := []map[string]string{}
+		// The item is indexed on the third poll
+		if polls.Add(1) >= 3 {
+			items = append(items, map[string]string{"Name": "Album", "Id": "abc", "Path": "/media/music/Album/track.flac"})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Items": items})
+	}))
+	defer srv.Close()
+
+	announcer := newJellyfinAnnouncer(jellyfin.New(srv.URL, "key", ""), "")
+	announcer.pollInterval = time.Millisecond
+	announcer.timeout = time.Second
+
+	item, ok := announcer.waitForItem("music", "Album")
+	if !ok || item.ID != "abc" {
+		t.Fatalf("expected the indexed item, got %+v %v", item, ok)
+	}
+
+	announcer.timeout = 10 * time.Millisecond
+	if _, ok := announcer.waitForItem("movies", "Missing"); ok {
+		t.Error("expected to give up on an item that never shows up")
+	}
+}
+
+func TestWatchInJellyfinKeyboardKeepsButtons(t *testing.T) {
+	notice := &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{{{Text: "📤 Send to chat", CallbackData: "/send 3"}}},
+	}
+	keyboard := watchInJellyfinKeyboard(notice, "https://media.example.org/web/#/details?id=abc")
+	if len(keyboard.InlineKeyboard) != 2 || keyboard.InlineKeyboard[0][0].CallbackData != "/send 3" {
+		t.Fatalf("expected the notice's buttons first, got %+v", keyboard.InlineKeyboard)
+	}
+	if keyboard.InlineKeyboard[1][0].Url == "" {
+		t.Errorf("expected the Jellyfin link last, got %+v", keyboard.InlineKeyboard[1])
+	}
+}
@@ -1,35 +1,60 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
-	"fmt"
-	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 	"time"
 
 	"github.com/minya/logger"
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/config"
 	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/jellyfin"
+	"github.com/minya/tgtorrentbot/metrics"
+	"github.com/minya/tgtorrentbot/preferences"
 	"github.com/odwrtw/transmission"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// requiredSettings are the settings the bot can't run without.
+var requiredSettings = []string{
+	"TGT_BOTTOKEN",
+	"TGT_WEBHOOKURL",
+	"TGT_DOWNLOADPATH",
+	"TGT_RPC_ADDR",
+	"TGT_RPC_USER",
+	"TGT_RPC_PASSWORD",
+	"TGT_RUTRACKER_USERNAME",
+	"TGT_RUTRACKER_PASSWORD",
+	"TGT_ALLOWED_USERS",
+}
+
+// shutdownTimeout is how long the updates in progress may take to finish on
+// SIGTERM; keep it below the container's stop grace period.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
-	settingsPath := flag.String("settings", "settings.json", "Path to settings file")
-	logLevelFlag := flag.String("log-level", "", "Log level (debug, info, warn, error) - overrides settings file/env")
+	configFlags := config.RegisterFlags(flag.CommandLine)
 	prettyLog := flag.Bool("pretty-log", true, "Enable pretty logging")
 	flag.Parse()
 
-	settings, err := ReadSettings(*settingsPath)
+	settings, err := config.Load(config.Options{
+		Flags:         configFlags,
+		Required:      requiredSettings,
+		ValidCategory: commands.IsValidCategory,
+	})
 	if err != nil {
 		logger.Fatal(err, "Failed to read settings")
 	}
 
-	// Determine log level priority: flag > settings > default
 	logLevel := "info"
-	if *logLevelFlag != "" {
-		logLevel = *logLevelFlag
-	} else if settings.LogLevel != "" {
+	if settings.LogLevel != "" {
 		logLevel = settings.LogLevel
 	}
 
@@ -40,41 +65,109 @@ func main() {
 	})
 
 	conf := transmission.Config{
-		Address:  settings.TransmissionRPC.Address,
-		User:     settings.TransmissionRPC.User,
-		Password: settings.TransmissionRPC.Password,
+		Address:    settings.TransmissionRPC.Address,
+		User:       settings.TransmissionRPC.User,
+		Password:   settings.TransmissionRPC.Password,
+		HTTPClient: metrics.TransmissionClient(),
 	}
 	transmissionClient, err := transmission.New(conf)
 	if err != nil {
 		logger.Fatal(err, "Can't create transmission client")
 	}
+	prometheus.MustRegister(torrentCollector{getTorrents: transmissionClient.GetTorrents})
+	metrics.Serve(settings.MetricsAddress, config.DefaultMetricsAddress)
 
 	api := telegram.NewApi(settings.BotToken)
-	notify := CreateCompletedCheckRoutine(transmissionClient, &api)
-
-	env := environment.Env{
-		TransmissionClient: transmissionClient,
-		TgApi:              &api,
-		DownloadPath:       settings.DownloadPath,
-		RutrackerConfig:    &settings.RutrackerConfig,
-		WebAppURL:          settings.WebAppURL,
-		AllowedUsers:       settings.AllowedUsers,
+	state, err := loadNotifierState(settings.StateFile)
+	if err != nil {
+		logger.Error(err, "Failed to load notifier state, starting fresh")
+	}
+	if settings.StateFile == "" {
+		logger.Warn("No state file configured; completions that happen while the bot is down will not be announced")
+	}
+	stallTimeout, err := parseStallTimeout(settings.StallTimeout)
+	if err != nil {
+		logger.Fatal(err, "Failed to read settings")
+	}
+	prefs, err := preferences.Load(settings.PreferencesFile)
+	if err != nil {
+		logger.Error(err, "Failed to load preferences, starting with defaults")
 	}
 
-	logger.Info("Access restricted to %d allowed user(s)", len(settings.AllowedUsers))
+	digestTime := settings.DigestTime
+	if digestTime == "" {
+		digestTime = defaultDigestTime
+	}
+	digestHour, digestMinute, err := parseDigestTime(digestTime)
+	if err != nil {
+		logger.Fatal(err, "Failed to read settings")
+	}
+	digest := &digestSchedule{
+		hour:         digestHour,
+		minute:       digestMinute,
+		location:     time.Local,
+		preferences:  prefs,
+		users:        settings.AllowedUsers,
+		downloadPath: settings.DownloadPath,
+	}
 
-	handler := NewUpdatesHandler(env, notify)
+	botConfig, err := parseBotSettings(settings)
+	if err != nil {
+		logger.Fatal(err, "Failed to read settings")
+	}
 
+	jellyfinClient := jellyfin.New(settings.JellyfinURL, settings.JellyfinAPIKey, settings.JellyfinMediaPath)
+	announcer := newJellyfinAnnouncer(jellyfinClient, settings.JellyfinPublicURL)
+	if announcer == nil {
+		logger.Info("Jellyfin is not configured; completed downloads wait for its scheduled scan")
+	}
 
-	webhookParams := telegram.SetWebhookParams{
-		Url:         settings.WebHookURL,
+	bot := botapi.New(settings.BotToken)
+	n := newNotifier(bot, prefs)
+	notify := CreateCompletedCheckRoutine(transmissionClient, n, state, checkerOptions{
+		stallTimeout:      stallTimeout,
+		digest:            digest,
+		hooks:             botConfig.completionHooks,
+		extractCategories: settings.ExtractCategories,
+		extractor:         newExtractor(),
+		jellyfin:          announcer,
+		downloadPath:      settings.DownloadPath,
+	})
+	startSeedingRoutine(transmissionClient, n, state, botConfig.seedingPolicies)
+	if !botConfig.seedingPolicies.Enabled() {
+		logger.Info("No seeding policies configured; completed torrents seed until removed")
 	}
 
-	err = api.SetWebhook(&webhookParams)
+	uploadBatchWindow, err := commands.ParseUploadBatchWindow(settings.UploadBatchWindow)
 	if err != nil {
-		logger.Fatal(err, "Failed to set webhook")
+		logger.Fatal(err, "Failed to read settings")
+	}
+
+	env := environment.Env{
+		TransmissionClient:  transmissionClient,
+		TgApi:               &api,
+		DownloadPath:        settings.DownloadPath,
+		RutrackerConfig:     &settings.RutrackerConfig,
+		WebAppURL:           settings.WebAppURL,
+		AllowedUsers:        settings.AllowedUsers,
+		AdminUsers:          settings.AdminUsers,
+		TrustedTorrentHosts: settings.TrustedTorrentHosts,
+		UploadBatchWindow:   uploadBatchWindow,
+		BotAPI:              bot,
+		Progress:            state,
+		Preferences:         prefs,
+		DigestTime:          digestTime,
+	}
+
+	logger.Info("Access restricted to %d allowed user(s)", len(settings.AllowedUsers))
+	for _, admin := range settings.AdminUsers {
+		if !slices.Contains(settings.AllowedUsers, admin) {
+			logger.Warn("Admin user %d is not in the allowed users list and will be ignored", admin)
+		}
 	}
 
+	handler := NewUpdatesHandler(env, notify)
+
 	// Set menu button to open webapp
 	if settings.WebAppURL != "" {
 		menuButtonParams := telegram.SetChatMenuButtonParams{
@@ -94,38 +187,47 @@ func main() {
 		}
 	}
 
-	startListen(80, handler.HandleUpdate)
-}
-
-func startListen(port int, handleUpdate func(*telegram.Update) error) {
-	logger.Info("Bot started")
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		var update telegram.Update
-		err := json.NewDecoder(r.Body).Decode(&update)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	d := newDispatcher(updateWorkers, updateQueueSize, handler.HandleUpdate)
 
-		if err != nil {
-			logger.Error(err, "Failed to parse update from request")
-			return
-		}
+	if settings.UpdateMode == config.UpdateModePolling {
+		startPolling(ctx, bot, d)
+	} else {
+		receiveWebhook(ctx, bot, settings, d)
+	}
 
-		// Respond 200 immediately so Telegram doesn't retry the update.
-		w.WriteHeader(http.StatusOK)
+	logger.Info("Shutting down, finishing the updates in progress")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = d.shutdown(shutdownCtx)
+	// Documents still waiting for more of their batch are prompted for now
+	commands.FlushUploadBatches()
+	if err != nil {
+		logger.Warn("Stopped before all updates were handled: %v", err)
+		return
+	}
+	logger.Info("All updates handled, exiting")
+}
 
-		go func() {
-			if err := handleUpdate(&update); err != nil {
-				logger.Error(err, "Failed to handle update from request")
-			}
-		}()
-	})
-	srv := &http.Server{
-		Addr:              fmt.Sprintf(":%d", port),
-		ReadHeaderTimeout: 10 * time.Second,
-		ReadTimeout:       30 * time.Second,
-		WriteTimeout:      30 * time.Second,
-		IdleTimeout:       120 * time.Second,
+// receiveWebhook points Telegram at the webhook and serves it until ctx is done.
+func receiveWebhook(ctx context.Context, bot *botapi.Client, settings config.Settings, d *dispatcher) {
+	secret := settings.WebhookSecret
+	if secret == "" {
+		secret = newWebhookSecret()
+	}
+	listener := webhookListener{
+		address:  settings.WebhookAddress,
+		port:     settings.WebhookPort,
+		path:     settings.WebhookPath,
+		certFile: settings.WebhookTLSCert,
+		keyFile:  settings.WebhookTLSKey,
+		secret:   secret,
 	}
-	if err := srv.ListenAndServe(); err != nil {
-		logger.Error(err, "Server failed")
-		os.Exit(1)
+
+	if err := setWebhook(bot, settings.WebHookURL, listener); err != nil {
+		logger.Fatal(err, "Failed to set webhook")
 	}
+
+	startListen(ctx, listener, d.dispatch)
 }
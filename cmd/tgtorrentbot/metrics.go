@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/metrics"
+	"github.com/odwrtw/transmission"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics of the bot; the services it calls are recorded by the metrics package.
+var (
+	updatesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_updates_total",
+		Help: "Updates from allowed users, by the command that handled them.",
+	}, []string{"command"})
+	commandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_command_errors_total",
+		Help: "Commands that returned an error, by command.",
+	}, []string{"command"})
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tgt_command_duration_seconds",
+		Help:    "Duration of commands, by command.",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"command"})
+	notificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_notifications_sent_total",
+		Help: "Notifications sent to users, by event.",
+	}, []string{"event"})
+)
+
+// noCommand labels updates no command accepted.
+const noCommand = "none"
+
+// commandName labels a command in the metrics by its type, e.g. SearchCommand.
+func commandName(cmd commands.Command) string {
+	name := fmt.Sprintf("%T", cmd)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+var (
+	torrentsDesc = prometheus.NewDesc("tgt_torrents",
+		"Torrents in Transmission, by state.", []string{"state"}, nil)
+	torrentBytesDesc = prometheus.NewDesc("tgt_torrent_bytes",
+		"Size of the torrents in Transmission, by category.", []string{"category"}, nil)
+)
+
+// torrentCollector reports the torrents in Transmission, read when the metrics
+// are scraped, so states and categories without torrents disappear.
+type torrentCollector struct {
+	getTorrents func() ([]*transmission.Torrent, error)
+}
+
+func (c torrentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- torrentsDesc
+	ch <- torrentBytesDesc
+}
+
+func (c torrentCollector) Collect(ch chan<- prometheus.Metric) {
+	torrents, err := c.getTorrents()
+	if err != nil {
+		// Leave the torrents out rather than fail the whole scrape
+		logger.Error(err, "[Metrics] Error getting torrents")
+		return
+	}
+	states := map[string]int{}
+	sizes := map[string]int64{}
+	for _, torrent := range torrents {
+		states[torrentState(torrent.Status)]++
+		category := getTorrentCategory(torrent)
+		if category == "" {
+			// Added outside the bot
+			category = "none"
+		}
+		sizes[category] += torrent.SizeWhenDone
+	}
+	for state, count := range states {
+		ch <- prometheus.MustNewConstMetric(torrentsDesc, prometheus.GaugeValue, float64(count), state)
+	}
+	for category, size := range sizes {
+		ch <- prometheus.MustNewConstMetric(torrentBytesDesc, prometheus.GaugeValue, float64(size), category)
+	}
+}
+
+func torrentState(status int) string {
+	switch status {
+	case transmission.StatusStopped:
+		return "stopped"
+	case transmission.StatusCheckPending:
+		return "check_pending"
+	case transmission.StatusChecking:
+		return "checking"
+	case transmission.StatusDownloadPending:
+		return "download_pending"
+	case transmission.StatusDownloading:
+		return "downloading"
+	case transmission.StatusSeedPending:
+		return "seed_pending"
+	case transmission.StatusSeeding:
+		return "seeding"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/odwrtw/transmission"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTorrentCollector(t *testing.T) {
+	var torrents []*transmission.Torrent
+	collector := torrentCollector{getTorrents: func() ([]*transmission.Torrent, error) {
+		return torrents, nil
+	}}
+
+	torrents = []*transmission.Torrent{
+		{Status: transmission.StatusSeeding, Labels: []string{"1", "movies"}, SizeWhenDone: 100},
+		{Status: transmission.StatusSeeding, Labels: []string{"1", "movies"}, SizeWhenDone: 50},
+		{Status: transmission.StatusDownloading, Labels: []string{"2", "music"}, SizeWhenDone: 10},
+		{Status: transmission.StatusStopped, SizeWhenDone: 5},
+	}
+	want := `# HELP tgt_torrent_bytes Size of the torrents in Transmission, by category.
+# TYPE tgt_torrent_bytes gauge
+tgt_torrent_bytes{category="movies"} 150
+tgt_torrent_bytes{category="music"} 10
+tgt_torrent_bytes{category="none"} 5
+# HELP tgt_torrents Torrents in Transmission, by state.
+# TYPE tgt_torrents gauge
+tgt_torrents{state="downloading"} 1
+tgt_torrents{state="seeding"} 2
+tgt_torrents{state="stopped"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// Categories without torrents disappear
+	torrents = nil
+	if got := testutil.CollectAndCount(collector); got != 0 {
+		t.Errorf("expected no series without torrents, got %d", got)
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	if got := commandName(&commands.SearchCommand{}); got != "SearchCommand" {
+		t.Errorf("got %q, want SearchCommand", got)
+	}
+}
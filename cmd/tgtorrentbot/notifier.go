@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/preferences"
+)
+
+// notifier sends the messages the bot sends on its own, honouring each
+// user's notification preferences.
+type notifier struct {
+	bot         *botapi.Client
+	preferences *preferences.Store
+	now         func() time.Time
+}
+
+func newNotifier(bot *botapi.Client, prefs *preferences.Store) *notifier {
+	return &notifier{bot: bot, preferences: prefs, now: time.Now}
+}
+
+// send delivers a message about the event and returns its ID, or 0 without
+// an error when the user turned the event off. Messages are silent during the
+// user's quiet hours or when they muted the bot.
+func (n *notifier) send(chatID int64, event preferences.Event, text string, markup any) (int64, error) {
+	return n.deliver(event, botapi.Message{ChatID: chatID, Text: text, ReplyMarkup: markup})
+}
+
+// reply is send for a message that answers an earlier one; it is sent on its
+// own if that message was deleted.
+func (n *notifier) reply(chatID int64, replyTo int64, event preferences.Event, text string, markup any) (int64, error) {
+	return n.deliver(event, botapi.Message{
+		ChatID:      chatID,
+		Text:        text,
+		ReplyMarkup: markup,
+		ReplyTo:     &botapi.ReplyParameters{MessageID: replyTo, AllowSendingWithoutReply: true},
+	})
+}
+
+func (n *notifier) deliver(event preferences.Event, msg botapi.Message) (int64, error) {
+	send, silent := n.preferences.Delivery(msg.ChatID, event, n.now())
+	if !send {
+		return 0, nil
+	}
+	msg.DisableNotification = silent
+	msgID, err := n.bot.SendMessage(msg)
+	if err == nil {
+		notificationsSent.WithLabelValues(string(event)).Inc()
+	}
+	return msgID, err
+}
+
+// edit changes the text and keyboard of a message the bot sent earlier; edits
+// never notify.
+func (n *notifier) edit(chatID int64, messageID int64, text string, markup *telegram.InlineKeyboardMarkup) error {
+	var replyMarkup any
+	if markup != nil {
+		replyMarkup = markup
+	}
+	return n.bot.EditMessageText(chatID, messageID, text, replyMarkup)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/odwrtw/transmission"
+)
+
+const (
+	// progressEditInterval is the minimum time between two edits of a progress message.
+	progressEditInterval = time.Minute
+	// maxProgressEditsPerCycle and maxProgressEditsPerChat keep a check cycle
+	// well within Telegram's rate limits.
+	maxProgressEditsPerCycle = 20
+	maxProgressEditsPerChat  = 5
+)
+
+// torrentProgress is what the completion notifier remembers about a torrent.
+type torrentProgress struct {
+	PercentDone float64 `json:"percentDone"`
+	Notified    bool    `json:"notified"`
+	// ChatID and MessageID identify the "Added" message that shows live progress.
+	ChatID    int64     `json:"chatID,omitempty"`
+	MessageID int64     `json:"messageID,omitempty"`
+	TrackedAt time.Time `json:"trackedAt,omitempty"`
+	LastEdit  time.Time `json:"lastEdit,omitempty"`
+	LastText  string    `json:"lastText,omitempty"`
+	// FirstSeen, ProgressAt and PeersAt feed stall detection; Alerted is the
+	// kind of problem already reported to the owner.
+	FirstSeen  time.Time `json:"firstSeen,omitempty"`
+	ProgressAt time.Time `json:"progressAt,omitempty"`
+	PeersAt    time.Time `json:"peersAt,omitempty"`
+	Alerted    string    `json:"alerted,omitempty"`
+}
+
+// progressEdit is a pending edit of a progress message. A message shared by
+// several torrents, such as the summary of a batch upload, lists them all.
+type progressEdit struct {
+	hashes    []string
+	chatID    int64
+	messageID int64
+	text      string
+	lastEdit  time.Time
+}
+
+// progressMessageKey identifies a progress message.
+type progressMessageKey struct {
+	chatID    int64
+	messageID int64
+}
+
+// notifierState is the completion notifier's view of Transmission, keyed by
+// torrent hash. It is persisted to a file so completions are not lost across restarts.
+type notifierState struct {
+	mu         sync.Mutex
+	path       string
+	LastCheck  time.Time                  `json:"lastCheck"`
+	LastDigest time.Time                  `json:"lastDigest,omitempty"`
+	Torrents   map[string]torrentProgress `json:"torrents"`
+}
+
+// loadNotifierState reads the state file; a missing file yields an empty state.
+// An empty path keeps the state in memory only.
+func loadNotifierState(path string) (*notifierState, error) {
+	state := &notifierState{path: path, Torrents: map[string]torrentProgress{}}
+	if path == "" {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("reading notifier state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return &notifierState{path: path, Torrents: map[string]torrentProgress{}}, fmt.Errorf("parsing notifier state: %w", err)
+	}
+	if state.Torrents == nil {
+		state.Torrents = map[string]torrentProgress{}
+	}
+	return state, nil
+}
+
+// TrackProgress remembers the message to edit with the torrent's progress.
+func (s *notifierState) TrackProgress(hash string, chatID int64, messageID int64) {
+	s.mu.Lock()
+	progress := s.Torrents[hash]
+	progress.ChatID = chatID
+	progress.MessageID = messageID
+	progress.TrackedAt = time.Now()
+	s.Torrents[hash] = progress
+	s.mu.Unlock()
+
+	if err := s.save(); err != nil {
+		logger.Error(err, "[UpdatesChecker] Error saving notifier state")
+	}
+}
+
+// messageEdit returns the edit that brings the progress message of a torrent
+// up to date, with completed torrents shown as completed.
+func (s *notifierState) messageEdit(torrents transmission.TorrentMap, hash string) (progressEdit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progress := s.Torrents[hash]
+	if progress.MessageID == 0 {
+		return progressEdit{}, false
+	}
+	key := progressMessageKey{chatID: progress.ChatID, messageID: progress.MessageID}
+	hashes := s.progressMessages(torrents)[key]
+	if len(hashes) == 0 {
+		return progressEdit{}, false
+	}
+	return s.renderMessage(key, hashes, torrents), true
+}
+
+// progressEdits returns the progress messages with incomplete torrents that are
+// due for an edit, least recently edited first and capped per cycle and per chat.
+func (s *notifierState) progressEdits(torrents transmission.TorrentMap, now time.Time) []progressEdit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var edits []progressEdit
+	for key, hashes := range s.progressMessages(torrents) {
+		// Messages whose torrents all completed are edited with the completion notice
+		incomplete := slices.ContainsFunc(hashes, func(hash string) bool { return torrents[hash].PercentDone < 1 })
+		if !incomplete {
+			continue
+		}
+		progress := s.Torrents[hashes[0]]
+		if now.Sub(progress.LastEdit) < progressEditInterval {
+			continue
+		}
+		edit := s.renderMessage(key, hashes, torrents)
+		if edit.text == progress.LastText {
+			continue
+		}
+		edits = append(edits, edit)
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].lastEdit.Before(edits[j].lastEdit) })
+
+	perChat := map[int64]int{}
+	limited := edits[:0]
+	for _, edit := range edits {
+		if len(limited) == maxProgressEditsPerCycle {
+			break
+		}
+		if perChat[edit.chatID] == maxProgressEditsPerChat {
+			continue
+		}
+		perChat[edit.chatID]++
+		limited = append(limited, edit)
+	}
+	return limited
+}
+
+// progressMessages groups the polled torrents by their progress message, in
+// the order they were tracked. The caller must hold the lock.
+func (s *notifierState) progressMessages(torrents transmission.TorrentMap) map[progressMessageKey][]string {
+	messages := map[progressMessageKey][]string{}
+	for hash := range torrents {
+		progress, ok := s.Torrents[hash]
+		if !ok || progress.MessageID == 0 {
+			continue
+		}
+		key := progressMessageKey{chatID: progress.ChatID, messageID: progress.MessageID}
+		messages[key] = append(messages[key], hash)
+	}
+	for _, hashes := range messages {
+		sort.Slice(hashes, func(i, j int) bool {
+			a, b := s.Torrents[hashes[i]], s.Torrents[hashes[j]]
+			if !a.TrackedAt.Equal(b.TrackedAt) {
+				return a.TrackedAt.Before(b.TrackedAt)
+			}
+			return hashes[i] < hashes[j]
+		})
+	}
+	return messages
+}
+
+// renderMessage builds the text of a progress message from its torrents. The
+// caller must hold the lock.
+func (s *notifierState) renderMessage(key progressMessageKey, hashes []string, torrents transmission.TorrentMap) progressEdit {
+	lines := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		torrent := torrents[hash]
+		if torrent.PercentDone >= 1 {
+			lines = append(lines, commands.FormatTorrentCompleted(torrent))
+		} else {
+			lines = append(lines, commands.FormatTorrentProgress(torrent))
+		}
+	}
+	return progressEdit{
+		hashes:    hashes,
+		chatID:    key.chatID,
+		messageID: key.messageID,
+		text:      strings.Join(lines, "\n\n"),
+		lastEdit:  s.Torrents[hashes[0]].LastEdit,
+	}
+}
+
+// save writes the state atomically so a crash never leaves a truncated file.
+func (s *notifierState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// pendingCompletions returns the hashes of completed torrents whose completion
+// has not been announced: torrents seen incomplete before, and torrents added
+// since the last check that finished while the bot was not polling. Completed
+// torrents seen for the first time on a fresh state are not announced.
+func (s *notifierState) pendingCompletions(torrents transmission.TorrentMap) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []string
+	for hash, torrent := range torrents {
+		if torrent.PercentDone < 1 {
+			continue
+		}
+		previous, known := s.Torrents[hash]
+		switch {
+		case known:
+			if !previous.Notified {
+				due = append(due, hash)
+			}
+		case !s.LastCheck.IsZero() && time.Unix(int64(torrent.AddedDate), 0).After(s.LastCheck):
+			due = append(due, hash)
+		}
+	}
+	return due
+}
+
+// alerts returns the problems of the polled torrents that have not been
+// reported yet, and the problems that were reported and still persist.
+func (s *notifierState) alerts(torrents transmission.TorrentMap, now time.Time, timeout time.Duration) ([]torrentAlert, map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []torrentAlert
+	ongoing := map[string]string{}
+	for hash, torrent := range torrents {
+		progress := s.Torrents[hash]
+		problem, ok := detectProblem(torrent, progress, now, timeout)
+		if !ok {
+			continue
+		}
+		if problem.kind == progress.Alerted {
+			ongoing[hash] = problem.kind
+			continue
+		}
+		due = append(due, torrentAlert{hash: hash, problem: problem})
+	}
+	return due, ongoing
+}
+
+// problems returns the descriptions of all current problems, keyed by hash.
+func (s *notifierState) problems(torrents transmission.TorrentMap, now time.Time, timeout time.Duration) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	problems := map[string]string{}
+	for hash, torrent := range torrents {
+		if problem, ok := detectProblem(torrent, s.Torrents[hash], now, timeout); ok {
+			problems[hash] = problem.description
+		}
+	}
+	return problems
+}
+
+// announced reports whether the completion notifier has handled the torrent's
+// completion, so its notice, hooks and extraction have started.
+func (s *notifierState) announced(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Torrents[hash].Notified
+}
+
+func (s *notifierState) lastDigest() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.LastDigest
+}
+
+func (s *notifierState) setLastDigest(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastDigest = t
+}
+
+// cycleResult is what a check cycle did: failed completion notices, progress
+// texts sent and problems reported to the owners.
+type cycleResult struct {
+	failed  map[string]bool
+	edited  map[string]string
+	alerted map[string]string
+}
+
+// record replaces the state with the current torrents polled at polledAt.
+// Completed torrents are marked notified unless their notice failed and should
+// be retried. Messages tracked after the poll are kept even though their
+// torrents were not polled yet.
+func (s *notifierState) record(torrents transmission.TorrentMap, result cycleResult, polledAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]torrentProgress, len(torrents))
+	for hash, torrent := range torrents {
+		progress, known := s.Torrents[hash]
+		complete := torrent.PercentDone == 1
+		downloading := torrent.Status == transmission.StatusDownloading
+
+		if progress.FirstSeen.IsZero() {
+			progress.FirstSeen = polledAt
+		}
+		// Paused or queued torrents are not stalled; their clocks restart when they resume.
+		if !known || !downloading || torrent.PercentDone > progress.PercentDone || progress.ProgressAt.IsZero() {
+			progress.ProgressAt = polledAt
+		}
+		if !known || !downloading || torrent.PeersConnected > 0 || progress.PeersAt.IsZero() {
+			progress.PeersAt = polledAt
+		}
+
+		progress.PercentDone = torrent.PercentDone
+		progress.Notified = complete && !result.failed[hash]
+		progress.Alerted = result.alerted[hash]
+		if text, ok := result.edited[hash]; ok {
+			progress.LastEdit = polledAt
+			progress.LastText = text
+		}
+		next[hash] = progress
+	}
+	for hash, progress := range s.Torrents {
+		if _, polled := torrents[hash]; !polled && progress.TrackedAt.After(polledAt) {
+			next[hash] = progress
+		}
+	}
+	s.Torrents = next
+	s.LastCheck = polledAt
+}
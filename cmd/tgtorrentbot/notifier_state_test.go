@@ -0,0 +1,170 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/odwrtw/transmission"
+)
+
+func TestNotifierStatePendingCompletions(t *testing.T) {
+	lastCheck := time.Unix(1000, 0)
+	state := &notifierState{
+		LastCheck: lastCheck,
+		Torrents: map[string]torrentProgress{
+			"finished-while-down": {PercentDone: 0.4},
+			"already-notified":    {PercentDone: 1, Notified: true},
+			"failed-notice":       {PercentDone: 1},
+			"still-downloading":   {PercentDone: 0.2},
+		},
+	}
+	torrents := transmission.TorrentMap{
+		"finished-while-down": {PercentDone: 1},
+		"already-notified":    {PercentDone: 1},
+		"failed-notice":       {PercentDone: 1},
+		"still-downloading":   {PercentDone: 0.5},
+		"added-while-down":    {PercentDone: 1, AddedDate: 2000},
+		"old-unknown":         {PercentDone: 1, AddedDate: 500},
+	}
+
+	due := state.pendingCompletions(torrents)
+	slices.Sort(due)
+	want := []string{"added-while-down", "failed-notice", "finished-while-down"}
+	if !slices.Equal(due, want) {
+		t.Errorf("pendingCompletions() = %v, want %v", due, want)
+	}
+}
+
+func TestNotifierStateFreshSkipsCompleted(t *testing.T) {
+	state := &notifierState{Torrents: map[string]torrentProgress{}}
+	torrents := transmission.TorrentMap{"done": {PercentDone: 1, AddedDate: 2000}}
+
+	if due := state.pendingCompletions(torrents); len(due) != 0 {
+		t.Errorf("expected no notices on a fresh state, got %v", due)
+	}
+}
+
+func TestNotifierStateRecordAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "notifier.json")
+	state, err := loadNotifierState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Unix(3000, 0)
+	state.record(transmission.TorrentMap{
+		"a": {PercentDone: 1},
+		"b": {PercentDone: 1},
+		"c": {PercentDone: 0.3},
+	}, cycleResult{failed: map[string]bool{"b": true}}, now)
+	if err := state.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := loadNotifierState(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !loaded.LastCheck.Equal(now) {
+		t.Errorf("LastCheck = %v, want %v", loaded.LastCheck, now)
+	}
+	if !loaded.Torrents["a"].Notified || loaded.Torrents["b"].Notified || loaded.Torrents["c"].Notified {
+		t.Errorf("unexpected notified flags %v", loaded.Torrents)
+	}
+	if loaded.Torrents["c"].PercentDone != 0.3 {
+		t.Errorf("expected percent to be persisted, got %v", loaded.Torrents["c"])
+	}
+}
+
+func TestNotifierStateProgressEdits(t *testing.T) {
+	now := time.Unix(10000, 0)
+	state := &notifierState{Torrents: map[string]torrentProgress{
+		"due":       {ChatID: 1, MessageID: 10, LastEdit: now.Add(-2 * progressEditInterval)},
+		"recent":    {ChatID: 1, MessageID: 11, LastEdit: now.Add(-progressEditInterval / 2)},
+		"done":      {ChatID: 1, MessageID: 12},
+		"untracked": {PercentDone: 0.5},
+	}}
+	torrents := transmission.TorrentMap{
+		"due":       {Name: "Due", PercentDone: 0.5},
+		"recent":    {Name: "Recent", PercentDone: 0.5},
+		"done":      {Name: "Done", PercentDone: 1},
+		"untracked": {Name: "Untracked", PercentDone: 0.5},
+	}
+
+	edits := state.progressEdits(torrents, now)
+	if len(edits) != 1 || !slices.Equal(edits[0].hashes, []string{"due"}) || edits[0].messageID != 10 {
+		t.Fatalf("unexpected edits %#v", edits)
+	}
+
+	state.record(torrents, cycleResult{edited: map[string]string{"due": edits[0].text}}, now)
+	if edits := state.progressEdits(torrents, now.Add(2*progressEditInterval)); len(edits) != 1 || edits[0].messageID != 11 {
+		t.Errorf("expected unchanged text not to be edited again, got %#v", edits)
+	}
+}
+
+func TestNotifierStateSharedProgressMessage(t *testing.T) {
+	tracked := time.Unix(1000, 0)
+	state := &notifierState{Torrents: map[string]torrentProgress{
+		"second": {ChatID: 1, MessageID: 10, TrackedAt: tracked.Add(time.Second)},
+		"first":  {ChatID: 1, MessageID: 10, TrackedAt: tracked},
+	}}
+	torrents := transmission.TorrentMap{
+		"first":  {Name: "First", PercentDone: 1, Labels: []string{"1", "movies"}},
+		"second": {Name: "Second", PercentDone: 0.5, Labels: []string{"1", "movies"}},
+	}
+
+	edits := state.progressEdits(torrents, tracked.Add(time.Hour))
+	if len(edits) != 1 || !slices.Equal(edits[0].hashes, []string{"first", "second"}) {
+		t.Fatalf("expected one edit of the shared message, got %#v", edits)
+	}
+	want := commands.FormatTorrentCompleted(torrents["first"]) + "\n\n" + commands.FormatTorrentProgress(torrents["second"])
+	if edits[0].text != want {
+		t.Errorf("text = %q, want %q", edits[0].text, want)
+	}
+
+	torrents["second"].PercentDone = 1
+	if edits := state.progressEdits(torrents, tracked.Add(time.Hour)); len(edits) != 0 {
+		t.Errorf("expected the completion notice to edit a finished message, got %#v", edits)
+	}
+	edit, ok := state.messageEdit(torrents, "second")
+	if !ok || edit.messageID != 10 || !strings.HasSuffix(edit.text, commands.FormatTorrentCompleted(torrents["second"])) {
+		t.Errorf("messageEdit() = %#v, %v", edit, ok)
+	}
+}
+
+func TestNotifierStateProgressEditsLimitedPerChat(t *testing.T) {
+	state := &notifierState{Torrents: map[string]torrentProgress{}}
+	torrents := transmission.TorrentMap{}
+	for i := 0; i < maxProgressEditsPerChat+3; i++ {
+		hash := string(rune('a' + i))
+		state.Torrents[hash] = torrentProgress{ChatID: 1, MessageID: int64(i + 1)}
+		torrents[hash] = &transmission.Torrent{Name: hash, PercentDone: 0.1}
+	}
+	state.Torrents["other"] = torrentProgress{ChatID: 2, MessageID: 100}
+	torrents["other"] = &transmission.Torrent{Name: "other", PercentDone: 0.1}
+
+	edits := state.progressEdits(torrents, time.Now())
+	if len(edits) != maxProgressEditsPerChat+1 {
+		t.Errorf("expected %d edits, got %d", maxProgressEditsPerChat+1, len(edits))
+	}
+}
+
+func TestNotifierStateRecordKeepsTrackedAfterPoll(t *testing.T) {
+	polledAt := time.Unix(5000, 0)
+	state := &notifierState{Torrents: map[string]torrentProgress{
+		"new":  {ChatID: 1, MessageID: 3, TrackedAt: polledAt.Add(time.Second)},
+		"gone": {ChatID: 1, MessageID: 4, TrackedAt: polledAt.Add(-time.Hour)},
+	}}
+
+	state.record(transmission.TorrentMap{}, cycleResult{}, polledAt)
+	if _, ok := state.Torrents["new"]; !ok {
+		t.Error("expected a message tracked after the poll to be kept")
+	}
+	if _, ok := state.Torrents["gone"]; ok {
+		t.Error("expected removed torrents to be dropped")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+)
+
+const (
+	// pollTimeout is how long Telegram holds a getUpdates request open.
+	pollTimeout = 50 * time.Second
+	// pollRetryMin and pollRetryMax bound the wait after a failed getUpdates.
+	pollRetryMin = time.Second
+	pollRetryMax = time.Minute
+)
+
+// updateSource is the part of the Bot API client the poller uses.
+type updateSource interface {
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]telegram.Update, error)
+}
+
+// startPolling receives updates with getUpdates instead of a webhook, which
+// needs no public URL. The webhook is deleted first, as Telegram refuses
+// getUpdates while one is set. It returns once ctx is done.
+func startPolling(ctx context.Context, bot *botapi.Client, d *dispatcher) {
+	for delay := pollRetryMin; ; delay = nextPollDelay(delay) {
+		err := bot.DeleteWebhook()
+		if err == nil {
+			break
+		}
+		logger.Error(err, "Failed to delete webhook, retrying in %s", delay)
+		if !sleepContext(ctx, delay) {
+			return
+		}
+	}
+	logger.Info("Bot started, polling for updates")
+
+	p := &poller{source: bot, dispatch: d.dispatch, sleep: sleepContext}
+	for ctx.Err() == nil {
+		p.poll(ctx)
+	}
+	p.confirm()
+}
+
+// poller tracks the offset of getUpdates: the ID after the last update
+// queued, which confirms everything before it to Telegram.
+type poller struct {
+	source   updateSource
+	dispatch func(context.Context, *telegram.Update) error
+	offset   int64
+	// delay is the wait before retrying after an error, 0 after a success.
+	delay time.Duration
+	sleep func(context.Context, time.Duration) bool
+}
+
+// poll fetches one batch of updates and queues them. Errors are retried with
+// a growing delay; updates that could not be queued are fetched again.
+func (p *poller) poll(ctx context.Context) {
+	updates, err := p.source.GetUpdates(ctx, p.offset, pollTimeout)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		p.delay = nextPollDelay(p.delay)
+		logger.Error(err, "Failed to get updates, retrying in %s", p.delay)
+		p.sleep(ctx, p.delay)
+		return
+	}
+	p.delay = 0
+
+	for i := range updates {
+		update := &updates[i]
+		if update.UpdateId < p.offset {
+			continue
+		}
+		if err := p.dispatch(ctx, update); err != nil {
+			logger.Warn("Update %d not queued, it will be fetched again: %v", update.UpdateId, err)
+			return
+		}
+		p.offset = update.UpdateId + 1
+	}
+}
+
+// confirm tells Telegram the updates queued since the last poll were
+// received, so they are not delivered again after a restart.
+func (p *poller) confirm() {
+	if p.offset == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p.source.GetUpdates(ctx, p.offset, 0); err != nil {
+		logger.Warn("Failed to confirm received updates: %v", err)
+	}
+}
+
+func nextPollDelay(delay time.Duration) time.Duration {
+	if delay < pollRetryMin {
+		return pollRetryMin
+	}
+	return min(delay*2, pollRetryMax)
+}
+
+// sleepContext waits for d and reports whether ctx is still not done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
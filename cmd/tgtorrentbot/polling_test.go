@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+)
+
+// fakeUpdates returns the queued results of getUpdates in order and records the offsets asked for.
+type fakeUpdates struct {
+	results []fakeResult
+	offsets []int64
+}
+
+type fakeResult struct {
+	updates []telegram.Update
+	err     error
+}
+
+func (f *fakeUpdates) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]telegram.Update, error) {
+	f.offsets = append(f.offsets, offset)
+	result := f.results[0]
+	f.results = f.results[1:]
+	return result.updates, result.err
+}
+
+func TestPoller(t *testing.T) {
+	source := &fakeUpdates{results: []fakeResult{
+		{updates: []telegram.Update{{UpdateId: 10}, {UpdateId: 11}}},
+		{err: errors.New("network down")},
+		{err: errors.New("network down")},
+		// Telegram resends what it was not told about; 11 was confirmed already
+		{updates: []telegram.Update{{UpdateId: 11}, {UpdateId: 12}}},
+		{},
+	}}
+
+	var handled []int64
+	var delays []time.Duration
+	p := &poller{
+		source: source,
+		dispatch: func(ctx context.Context, upd *telegram.Update) error {
+			handled = append(handled, upd.UpdateId)
+			return nil
+		},
+		sleep: func(ctx context.Context, d time.Duration) bool {
+			delays = append(delays, d)
+			return true
+		},
+	}
+
+	for range source.results {
+		p.poll(context.Background())
+	}
+
+	wantOffsets := []int64{0, 12, 12, 12, 13}
+	if len(source.offsets) != len(wantOffsets) {
+		t.Fatalf("got offsets %v, want %v", source.offsets, wantOffsets)
+	}
+	for i, offset := range wantOffsets {
+		if source.offsets[i] != offset {
+			t.Errorf("got offsets %v, want %v", source.offsets, wantOffsets)
+			break
+		}
+	}
+	if len(handled) != 3 {
+		t.Errorf("expected updates 10, 11 and 12 to be handled once, got %v", handled)
+	}
+	if len(delays) != 2 || delays[0] != pollRetryMin || delays[1] != 2*pollRetryMin {
+		t.Errorf("expected growing retry delays, got %v", delays)
+	}
+	if p.delay != 0 {
+		t.Errorf("expected the delay to reset after a success, got %s", p.delay)
+	}
+}
+
+func TestPoller_NotQueued(t *testing.T) {
+	source := &fakeUpdates{results: []fakeResult{
+		{updates: []telegram.Update{{UpdateId: 10}, {UpdateId: 11}, {UpdateId: 12}}},
+		{},
+	}}
+	p := &poller{
+		source: source,
+		dispatch: func(ctx context.Context, upd *telegram.Update) error {
+			if upd.UpdateId == 11 {
+				return errDispatcherClosed
+			}
+			return nil
+		},
+	}
+
+	p.poll(context.Background())
+	if p.offset != 11 {
+		t.Errorf("expected the offset to stop before the update not queued, got %d", p.offset)
+	}
+
+	// Only what was queued is confirmed on the way out
+	p.confirm()
+	if len(source.offsets) != 2 || source.offsets[1] != 11 {
+		t.Errorf("expected offset 11 to be confirmed, got %v", source.offsets)
+	}
+}
+
+func TestNextPollDelay(t *testing.T) {
+	if got := nextPollDelay(0); got != pollRetryMin {
+		t.Errorf("got %s, want %s", got, pollRetryMin)
+	}
+	if got := nextPollDelay(pollRetryMax); got != pollRetryMax {
+		t.Errorf("got %s, want the delay capped at %s", got, pollRetryMax)
+	}
+}
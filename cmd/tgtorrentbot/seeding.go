@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/preferences"
+	"github.com/minya/tgtorrentbot/seeding"
+	"github.com/odwrtw/transmission"
+)
+
+// seedingCheckInterval is how often the seeding policies are enforced.
+const seedingCheckInterval = 10 * time.Minute
+
+// seeder enforces the seeding policies on completed torrents and tells their
+// owners what it did.
+type seeder struct {
+	client *transmission.Client
+	n      *notifier
+	engine *seeding.Engine
+	// state tells which completions the notifier has handled.
+	state *notifierState
+	// stopped are torrents the policies paused, so that one the owner resumes
+	// afterwards keeps seeding until the bot restarts.
+	stopped map[string]bool
+}
+
+// startSeedingRoutine enforces the policies at startup and then periodically,
+// independently of the completion notifier, which pauses while nothing downloads.
+func startSeedingRoutine(client *transmission.Client, n *notifier, state *notifierState, engine *seeding.Engine) {
+	if !engine.Enabled() {
+		return
+	}
+	s := newSeeder(client, n, state, engine)
+	go func() {
+		ticker := time.NewTicker(seedingCheckInterval)
+		defer ticker.Stop()
+		for {
+			s.check(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+func newSeeder(client *transmission.Client, n *notifier, state *notifierState, engine *seeding.Engine) *seeder {
+	return &seeder{client: client, n: n, engine: engine, state: state, stopped: map[string]bool{}}
+}
+
+func (s *seeder) check(now time.Time) {
+	torrents, err := s.client.GetTorrents()
+	if err != nil {
+		logger.Error(err, "[Seeding] Error getting torrents")
+		return
+	}
+
+	for _, torrent := range torrents {
+		if !isSeedingCandidate(torrent) || s.stopped[torrent.HashString] {
+			continue
+		}
+		// A torrent removed before its completion was handled would lose its
+		// notice, hooks and extraction, so it waits for the notifier
+		if !s.state.announced(torrent.HashString) {
+			continue
+		}
+		decision, ok := s.engine.Decide(seedingTorrent(torrent), now)
+		if !ok || (decision.Action == seeding.ActionStop && torrent.Status == transmission.StatusStopped) {
+			continue
+		}
+
+		logger.Info("[Seeding] Applying %s to %s: %s", decision.Action, torrent.Name, decision.Reason)
+		if err := applySeedingDecision(s.client, torrent, decision); err != nil {
+			logger.Error(err, "[Seeding] Error applying %s to %s, will retry", decision.Action, torrent.Name)
+			continue
+		}
+		if decision.Action == seeding.ActionStop {
+			s.stopped[torrent.HashString] = true
+		}
+
+		chatID, err := getTorrentChatID(torrent)
+		if err != nil {
+			continue
+		}
+		if _, err := s.n.send(chatID, preferences.EventSeeding, commands.FormatSeedingAction(torrent, decision), nil); err != nil {
+			logger.Error(err, "[Seeding] Error notifying the owner of %s", torrent.Name)
+		}
+	}
+}
+
+// isSeedingCandidate reports whether the torrent finished downloading and is
+// not being verified. Stopped torrents count, so a remove policy still applies
+// to torrents Transmission paused on its own ratio limit.
+func isSeedingCandidate(torrent *transmission.Torrent) bool {
+	if torrent.PercentDone < 1 || torrent.LeftUntilDone > 0 {
+		return false
+	}
+	switch torrent.Status {
+	case transmission.StatusStopped, transmission.StatusSeedPending, transmission.StatusSeeding:
+		return true
+	default:
+		return false
+	}
+}
+
+// seedingTorrent describes a torrent to the policies. Torrents completed
+// before Transmission recorded a done date count from when they were added.
+func seedingTorrent(torrent *transmission.Torrent) seeding.Torrent {
+	doneAt := torrent.DoneDate
+	if doneAt == 0 {
+		doneAt = torrent.AddedDate
+	}
+	var done time.Time
+	if doneAt > 0 {
+		done = time.Unix(int64(doneAt), 0)
+	}
+	return seeding.Torrent{
+		Category: getTorrentCategory(torrent),
+		Ratio:    torrent.UploadRatio,
+		DoneAt:   done,
+	}
+}
+
+func applySeedingDecision(client *transmission.Client, torrent *transmission.Torrent, decision seeding.Decision) error {
+	if decision.Action == seeding.ActionRemove {
+		return client.RemoveTorrents([]*transmission.Torrent{torrent}, false)
+	}
+	return torrent.Stop()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minya/tgtorrentbot/seeding"
+	"github.com/odwrtw/transmission"
+)
+
+func TestIsSeedingCandidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		torrent transmission.Torrent
+		want    bool
+	}{
+		{"seeding", transmission.Torrent{PercentDone: 1, Status: transmission.StatusSeeding}, true},
+		{"stopped after completion", transmission.Torrent{PercentDone: 1, Status: transmission.StatusStopped}, true},
+		{"downloading", transmission.Torrent{PercentDone: 0.5, LeftUntilDone: 100, Status: transmission.StatusDownloading}, false},
+		{"paused download", transmission.Torrent{PercentDone: 0.5, LeftUntilDone: 100, Status: transmission.StatusStopped}, false},
+		{"verifying", transmission.Torrent{PercentDone: 1, Status: transmission.StatusChecking}, false},
+	}
+	for _, tt := range tests {
+		if got := isSeedingCandidate(&tt.torrent); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSeedingTorrent(t *testing.T) {
+	torrent := &transmission.Torrent{
+		Labels:      []string{"42", "movies"},
+		UploadRatio: 1.5,
+		AddedDate:   1700000000,
+		DoneDate:    1700003600,
+	}
+	got := seedingTorrent(torrent)
+	if got.Category != "movies" || got.Ratio != 1.5 || !got.DoneAt.Equal(time.Unix(1700003600, 0)) {
+		t.Errorf("unexpected %+v", got)
+	}
+
+	// Without a done date the torrent counts from when it was added
+	torrent.DoneDate = 0
+	if got := seedingTorrent(torrent); !got.DoneAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected the added date, got %v", got.DoneAt)
+	}
+}
+
+func TestSeederWaitsForCompletionNotice(t *testing.T) {
+	var mu sync.Mutex
+	var stopped []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method    string         `json:"method"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "torrent-stop" {
+			mu.Lock()
+			stopped = append(stopped, req.Arguments["ids"])
+			mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"result": "success",
+			"arguments": map[string]any{"torrents": []map[string]any{
+				{"id": 1, "hashString": "abc", "name": "Dune", "percentDone": 1, "status": transmission.StatusSeeding, "labels": []string{"42", "movies"}},
+				{"id": 2, "hashString": "def", "name": "Arrival", "percentDone": 1, "status": transmission.StatusSeeding, "labels": []string{"42", "movies"}},
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	client, err := transmission.New(transmission.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot, _ := newTestBot(t, `{"ok":true,"result":{"message_id":11}}`)
+	engine, err := seeding.New(seeding.Config{seeding.AnyCategory: {Action: seeding.ActionStop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only Dune's completion has been announced
+	state := &notifierState{Torrents: map[string]torrentProgress{
+		"abc": {PercentDone: 1, Notified: true},
+		"def": {PercentDone: 1},
+	}}
+
+	newSeeder(client, newNotifier(bot, nil), state, engine).check(time.Now())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stopped) != 1 || stopped[0] != float64(1) {
+		t.Errorf("expected only the announced torrent to be stopped, got %v", stopped)
+	}
+}
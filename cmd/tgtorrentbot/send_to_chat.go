@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/odwrtw/transmission"
+)
+
+// maxSendFiles is the most files sent one by one; bigger folders can only go as a ZIP.
+const maxSendFiles = 20
+
+// audioExtensions are sent with sendAudio so they play in Telegram's player.
+var audioExtensions = map[string]bool{
+	".mp3": true, ".m4a": true, ".m4b": true, ".ogg": true, ".opus": true, ".flac": true, ".aac": true, ".wav": true,
+}
+
+// localFile is a file of a completed download.
+type localFile struct {
+	path string
+	// name is relative to the download's folder
+	name string
+	size int64
+}
+
+// downloadPath is where a torrent's data is, from the bot's download path and
+// the torrent's category, or "" for torrents the bot can't place.
+func downloadPath(root string, torrent *transmission.Torrent) string {
+	category := getTorrentCategory(torrent)
+	if !commands.IsValidCategory(category) || !filepath.IsLocal(torrent.Name) {
+		return ""
+	}
+	return filepath.Join(root, category, torrent.Name)
+}
+
+// listDownload lists the regular files of a download, which is either a single
+// file or a folder. Extraction markers are left out.
+func listDownload(path string) ([]localFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []localFile{{path: path, name: info.Name(), size: info.Size()}}, nil
+	}
+
+	var files []localFile
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() == extract.MarkerFile {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(path, p)
+		files = append(files, localFile{path: p, name: filepath.ToSlash(name), size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// sendOptions reports whether a download can be sent file by file and whether
+// it can be sent as a single ZIP within the Bot API upload limit.
+func sendOptions(files []localFile) (separately bool, zipped bool) {
+	if len(files) == 0 {
+		return false, false
+	}
+	var total int64
+	separately = len(files) <= maxSendFiles
+	for _, file := range files {
+		total += file.size
+		if file.size > botapi.MaxUploadSize {
+			separately = false
+		}
+	}
+	// Media barely compresses, so only offer archives that fit uncompressed
+	zipped = len(files) > 1 && total <= botapi.MaxUploadSize
+	return separately, zipped
+}
+
+// sendToChatButtons offers to send a small completed download into the chat.
+func sendToChatButtons(root string, torrent *transmission.Torrent) []telegram.InlineKeyboardButton {
+	path := downloadPath(root, torrent)
+	if path == "" {
+		return nil
+	}
+	files, err := listDownload(path)
+	if err != nil {
+		logger.Warn("[UpdatesChecker] Can't list files of %s: %v", torrent.Name, err)
+		return nil
+	}
+
+	var buttons []telegram.InlineKeyboardButton
+	separately, zipped := sendOptions(files)
+	if separately {
+		buttons = append(buttons, telegram.InlineKeyboardButton{
+			Text:         "📤 Send to chat",
+			CallbackData: fmt.Sprintf("/send %d", torrent.ID),
+		})
+	}
+	if zipped {
+		buttons = append(buttons, telegram.InlineKeyboardButton{
+			Text:         "🗜 Send as ZIP",
+			CallbackData: fmt.Sprintf("/sendzip %d", torrent.ID),
+		})
+	}
+	return buttons
+}
+
+// SendToChatCommand uploads a completed download into the chat: /send <id>, /sendzip <id>
+type SendToChatCommand struct {
+	TorrentID int
+	Zip       bool
+	environment.Env
+}
+
+type SendToChatCommandFactory struct {
+	environment.Env
+}
+
+var reSendToChatCmd = regexp.MustCompile(`^/send(zip)?\s+(\d+)$`)
+
+// sending keeps a download from being uploaded twice when the button is tapped again.
+var sending sync.Map
+
+func (factory *SendToChatCommandFactory) Accepts(upd *telegram.Update) (bool, commands.Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	found := reSendToChatCmd.FindStringSubmatch(upd.CallbackQuery.Data)
+	if found == nil {
+		return false, nil
+	}
+	torrentID, err := strconv.Atoi(found[2])
+	if err != nil {
+		return false, nil
+	}
+	return true, &SendToChatCommand{TorrentID: torrentID, Zip: found[1] != "", Env: factory.Env}
+}
+
+func (cmd *SendToChatCommand) Handle(upd *telegram.Update) error {
+	commands.AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+	reply := func(text string) {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{ChatId: chatID, Text: text})
+	}
+
+	torrent, err := cmd.findTorrent(chatID)
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		return err
+	}
+	if torrent == nil {
+		reply(fmt.Sprintf("Torrent %d not found", cmd.TorrentID))
+		return nil
+	}
+	path := downloadPath(cmd.DownloadPath, torrent)
+	if path == "" {
+		reply("Can't find the files of this torrent")
+		return nil
+	}
+	files, err := listDownload(path)
+	if err != nil {
+		logger.Error(err, "Error listing %s", path)
+		reply("Can't find the files of this torrent")
+		return nil
+	}
+
+	separately, zipped := sendOptions(files)
+	if (cmd.Zip && !zipped) || (!cmd.Zip && !separately) {
+		reply(fmt.Sprintf("%s is too big to send to Telegram", torrent.Name))
+		return nil
+	}
+
+	if _, busy := sending.LoadOrStore(torrent.HashString, true); busy {
+		reply(fmt.Sprintf("%s is already being sent", torrent.Name))
+		return nil
+	}
+	defer sending.Delete(torrent.HashString)
+
+	if cmd.Zip {
+		err = sendZip(cmd.BotAPI, chatID, torrent.Name, files)
+	} else {
+		err = sendFiles(cmd.BotAPI, chatID, files)
+	}
+	if err != nil {
+		logger.Error(err, "Error sending %s to chat", torrent.Name)
+		reply(fmt.Sprintf("Failed to send %s: %v", torrent.Name, err))
+		return err
+	}
+	return nil
+}
+
+// findTorrent returns the chat's torrent with the command's ID, or nil.
+func (cmd *SendToChatCommand) findTorrent(chatID int64) (*transmission.Torrent, error) {
+	torrents, err := cmd.TransmissionClient.GetTorrents()
+	if err != nil {
+		return nil, err
+	}
+	for _, torrent := range torrents {
+		if torrent.ID != cmd.TorrentID {
+			continue
+		}
+		if owner, err := getTorrentChatID(torrent); err != nil || owner != chatID {
+			return nil, nil
+		}
+		return torrent, nil
+	}
+	return nil, nil
+}
+
+func sendFiles(bot *botapi.Client, chatID int64, files []localFile) error {
+	for _, file := range files {
+		err := func() error {
+			f, err := os.Open(file.path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			upload := botapi.Upload{ChatID: chatID, Name: filepath.Base(file.path), Content: f, Caption: file.name}
+			if audioExtensions[strings.ToLower(filepath.Ext(file.path))] {
+				return bot.SendAudio(upload)
+			}
+			return bot.SendDocument(upload)
+		}()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return nil
+}
+
+// sendZip packs the files into a temporary ZIP and sends it as a document.
+func sendZip(bot *botapi.Client, chatID int64, name string, files []localFile) error {
+	tmp, err := os.CreateTemp("", "tgt-send-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := writeZip(tmp, files); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > botapi.MaxUploadSize {
+		return fmt.Errorf("the archive is larger than %d MB", botapi.MaxUploadSize/1024/1024)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return bot.SendDocument(botapi.Upload{ChatID: chatID, Name: name + ".zip", Content: tmp})
+}
+
+func writeZip(w io.Writer, files []localFile) error {
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		if err := addToZip(archive, file); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+func addToZip(archive *zip.Writer, file localFile) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, f)
+	return err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/odwrtw/transmission"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListDownload(t *testing.T) {
+	root := t.TempDir()
+	album := filepath.Join(root, "music", "Album")
+	writeTestFile(t, filepath.Join(album, "01.flac"), "one")
+	writeTestFile(t, filepath.Join(album, "CD2", "02.flac"), "two!")
+	writeTestFile(t, filepath.Join(album, extract.MarkerFile), "{}")
+
+	files, err := listDownload(album)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].name != "01.flac" || files[1].name != "CD2/02.flac" || files[1].size != 4 {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	single := filepath.Join(root, "books", "book.epub")
+	writeTestFile(t, single, "epub")
+	files, err = listDownload(single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].name != "book.epub" {
+		t.Errorf("unexpected files %+v", files)
+	}
+}
+
+func TestSendOptions(t *testing.T) {
+	small := localFile{name: "a", size: 1024}
+	huge := localFile{name: "b", size: botapi.MaxUploadSize + 1}
+	many := make([]localFile, maxSendFiles+1)
+	for i := range many {
+		many[i] = small
+	}
+
+	tests := []struct {
+		name       string
+		files      []localFile
+		separately bool
+		zipped     bool
+	}{
+		{"nothing", nil, false, false},
+		{"single file", []localFile{small}, true, false},
+		{"few files", []localFile{small, small}, true, true},
+		{"too many files", many, false, true},
+		{"file too big", []localFile{huge}, false, false},
+		{"folder too big", []localFile{small, huge}, false, false},
+	}
+	for _, tt := range tests {
+		separately, zipped := sendOptions(tt.files)
+		if separately != tt.separately || zipped != tt.zipped {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.name, separately, zipped, tt.separately, tt.zipped)
+		}
+	}
+}
+
+func TestCompletionKeyboard(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "music", "Album", "01.flac"), "one")
+	writeTestFile(t, filepath.Join(root, "music", "Album", "02.flac"), "two")
+	torrent := &transmission.Torrent{ID: 7, Name: "Album", Labels: []string{"42", "music"}}
+
+	keyboard := completionKeyboard(root, torrent)
+	if keyboard == nil || len(keyboard.InlineKeyboard) != 1 {
+		t.Fatalf("expected one row of buttons, got %+v", keyboard)
+	}
+	row := keyboard.InlineKeyboard[0]
+	if len(row) != 2 || row[0].CallbackData != "/send 7" || row[1].CallbackData != "/sendzip 7" {
+		t.Errorf("unexpected buttons %+v", row)
+	}
+
+	// Torrents the bot can't place get no buttons
+	for _, torrent := range []*transmission.Torrent{
+		{ID: 8, Name: "Album"},
+		{ID: 9, Name: "../Album", Labels: []string{"42", "music"}},
+		{ID: 10, Name: "Missing", Labels: []string{"42", "music"}},
+	} {
+		if keyboard := completionKeyboard(root, torrent); keyboard != nil {
+			t.Errorf("%s: expected no buttons, got %+v", torrent.Name, keyboard)
+		}
+	}
+	if keyboard := completionKeyboard("", torrent); keyboard != nil {
+		t.Errorf("expected no buttons without a download path, got %+v", keyboard)
+	}
+}
+
+func TestSendToChatCommandFactory(t *testing.T) {
+	factory := &SendToChatCommandFactory{}
+	tests := []struct {
+		data string
+		ok   bool
+		zip  bool
+	}{
+		{"/send 3", true, false},
+		{"/sendzip 3", true, true},
+		{"/send", false, false},
+		{"/sendall 3", false, false},
+	}
+	for _, tt := range tests {
+		upd := &telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: tt.data}}
+		ok, cmd := factory.Accepts(upd)
+		if ok != tt.ok {
+			t.Errorf("%q: got %v, want %v", tt.data, ok, tt.ok)
+			continue
+		}
+		if ok && (cmd.(*SendToChatCommand).TorrentID != 3 || cmd.(*SendToChatCommand).Zip != tt.zip) {
+			t.Errorf("%q: unexpected command %+v", tt.data, cmd)
+		}
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "01.flac"), "one")
+	writeTestFile(t, filepath.Join(root, "CD2", "02.flac"), "two")
+	files, err := listDownload(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeZip(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != "01.flac" || r.File[1].Name != "CD2/02.flac" {
+		t.Errorf("unexpected archive entries %+v", r.File)
+	}
+}
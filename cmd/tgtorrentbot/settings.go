@@ -1,22 +1,71 @@
 package main
 
 import (
-	"github.com/minya/rutracker"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/completionhooks"
+	"github.com/minya/tgtorrentbot/config"
+	"github.com/minya/tgtorrentbot/seeding"
 )
 
-type Settings struct {
-	BotToken        string                  `json:"botToken"`
-	WebHookURL      string                  `json:"webHookURL"`
-	DownloadPath    string                  `json:"downloadPath"`
-	TransmissionRPC TransmissionRPCSettings `json:"transmissionRPC"`
-	RutrackerConfig rutracker.Config        `json:"rutrackerConfig"`
-	LogLevel        string                  `json:"logLevel"`
-	WebAppURL       string                  `json:"webAppURL"`
-	AllowedUsers    []int64                 `json:"allowedUsers"`
+// botSettings are the settings only the bot parses; the shared config keeps
+// them as JSON so the Mini App doesn't depend on the bot's packages.
+type botSettings struct {
+	completionHooks *completionhooks.Runner
+	seedingPolicies *seeding.Engine
+}
+
+// parseBotSettings parses the completion hooks and seeding policies and checks
+// their categories. Every problem found is reported in the one error.
+func parseBotSettings(settings config.Settings) (botSettings, error) {
+	var parsed botSettings
+	var problems []string
+
+	var hooksConfig completionhooks.Config
+	if err := unmarshalSetting(settings.CompletionHooks, &hooksConfig); err != nil {
+		problems = append(problems, fmt.Sprintf("completion hooks: %v", err))
+	}
+	runner, err := completionhooks.New(hooksConfig)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	parsed.completionHooks = runner
+	for _, category := range slices.Sorted(maps.Keys(hooksConfig)) {
+		if category != completionhooks.AnyCategory && !commands.IsValidCategory(category) {
+			problems = append(problems, fmt.Sprintf("unknown completion hook category %q", category))
+		}
+	}
+
+	var policies seeding.Config
+	if err := unmarshalSetting(settings.SeedingPolicies, &policies); err != nil {
+		problems = append(problems, fmt.Sprintf("seeding policies: %v", err))
+	}
+	engine, err := seeding.New(policies)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	parsed.seedingPolicies = engine
+	for _, category := range slices.Sorted(maps.Keys(policies)) {
+		if category != seeding.AnyCategory && !commands.IsValidCategory(category) {
+			problems = append(problems, fmt.Sprintf("unknown seeding policy category %q", category))
+		}
+	}
+
+	if len(problems) > 0 {
+		return parsed, fmt.Errorf("config problems: %s", strings.Join(problems, "; "))
+	}
+	return parsed, nil
 }
 
-type TransmissionRPCSettings struct {
-	Address  string `json:"address"`
-	User     string `json:"user"`
-	Password string `json:"password"`
+// unmarshalSetting parses a JSON setting; an unset one leaves v empty.
+func unmarshalSetting(data json.RawMessage, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
 }
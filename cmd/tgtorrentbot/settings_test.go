@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/minya/tgtorrentbot/config"
+)
+
+func TestParseBotSettings(t *testing.T) {
+	parsed, err := parseBotSettings(config.Settings{
+		CompletionHooks: json.RawMessage(`{"*": [{"command": ["/bin/true"]}]}`),
+		SeedingPolicies: json.RawMessage(`{"movies": {"ratio": 2}}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.completionHooks == nil || !parsed.seedingPolicies.Enabled() {
+		t.Errorf("unexpected %+v", parsed)
+	}
+
+	// Unset settings configure nothing
+	parsed, err = parseBotSettings(config.Settings{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.seedingPolicies.Enabled() {
+		t.Error("expected no seeding policies")
+	}
+}
+
+func TestParseBotSettings_ReportsAllProblems(t *testing.T) {
+	_, err := parseBotSettings(config.Settings{
+		CompletionHooks: json.RawMessage(`{"games": [{"command": ["/bin/true"]}]}`),
+		SeedingPolicies: json.RawMessage(`{"movies": {"ratio": -1}, "books": {"days": 1}}`),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`unknown completion hook category "games"`,
+		"invalid seeding policies",
+		`unknown seeding policy category "books"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+}
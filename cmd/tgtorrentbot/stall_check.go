@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/odwrtw/transmission"
+)
+
+// Kinds of torrent problems the update checker alerts about
+const (
+	problemError      = "error"
+	problemMetadata   = "metadata"
+	problemNoPeers    = "no-peers"
+	problemNoProgress = "no-progress"
+)
+
+// Transmission's torrent error codes (the "error" field of torrent-get)
+const (
+	torrentErrorTracker = 2
+	torrentErrorLocal   = 3
+)
+
+// defaultStallTimeout is used when no stall timeout is configured.
+const defaultStallTimeout = 2 * time.Hour
+
+// torrentProblem is an error or stall detected on a torrent.
+type torrentProblem struct {
+	kind        string
+	description string
+}
+
+// torrentAlert is a problem that has not been reported to the owner yet.
+type torrentAlert struct {
+	hash    string
+	problem torrentProblem
+}
+
+// detectProblem checks a torrent for Transmission errors and, while it is
+// downloading, for missing metadata, peers or progress for longer than the timeout.
+func detectProblem(torrent *transmission.Torrent, progress torrentProgress, now time.Time, timeout time.Duration) (torrentProblem, bool) {
+	incomplete := torrent.PercentDone < 1
+	if torrent.Status != transmission.StatusStopped &&
+		(torrent.Error == torrentErrorLocal || (torrent.Error == torrentErrorTracker && incomplete)) {
+		return torrentProblem{problemError, fmt.Sprintf("Error: %s", torrent.ErrorString)}, true
+	}
+	if !incomplete || torrent.Status != transmission.StatusDownloading || progress.FirstSeen.IsZero() {
+		return torrentProblem{}, false
+	}
+
+	switch {
+	case torrent.MetadataPercentComplete < 1 && now.Sub(progress.FirstSeen) >= timeout:
+		return torrentProblem{problemMetadata, fmt.Sprintf("Stuck on metadata for %s", formatStallDuration(now.Sub(progress.FirstSeen)))}, true
+	case torrent.PeersConnected == 0 && now.Sub(progress.PeersAt) >= timeout:
+		return torrentProblem{problemNoPeers, fmt.Sprintf("No peers for %s", formatStallDuration(now.Sub(progress.PeersAt)))}, true
+	case now.Sub(progress.ProgressAt) >= timeout:
+		return torrentProblem{problemNoProgress, fmt.Sprintf("No download progress for %s", formatStallDuration(now.Sub(progress.ProgressAt)))}, true
+	}
+	return torrentProblem{}, false
+}
+
+func formatStallDuration(d time.Duration) string {
+	return d.Truncate(time.Minute).String()
+}
+
+// parseStallTimeout parses the configured stall timeout, falling back to the default.
+func parseStallTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultStallTimeout, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stall timeout %q: %w", s, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("stall timeout must be positive, got %s", s)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/odwrtw/transmission"
+)
+
+func TestDetectProblem(t *testing.T) {
+	now := time.Unix(100000, 0)
+	timeout := time.Hour
+	fresh := torrentProgress{FirstSeen: now.Add(-time.Minute), ProgressAt: now, PeersAt: now}
+	stale := now.Add(-2 * time.Hour)
+
+	downloading := func(mutate func(*transmission.Torrent)) *transmission.Torrent {
+		torrent := &transmission.Torrent{Status: transmission.StatusDownloading, PercentDone: 0.03, MetadataPercentComplete: 1, PeersConnected: 3}
+		if mutate != nil {
+			mutate(torrent)
+		}
+		return torrent
+	}
+
+	cases := []struct {
+		name     string
+		torrent  *transmission.Torrent
+		progress torrentProgress
+		want     string
+	}{
+		{"healthy", downloading(nil), fresh, ""},
+		{"tracker error", downloading(func(t *transmission.Torrent) { t.Error = torrentErrorTracker; t.ErrorString = "unregistered" }), fresh, problemError},
+		{"metadata", downloading(func(t *transmission.Torrent) { t.MetadataPercentComplete = 0 }), torrentProgress{FirstSeen: stale, ProgressAt: now, PeersAt: now}, problemMetadata},
+		{"no peers", downloading(func(t *transmission.Torrent) { t.PeersConnected = 0 }), torrentProgress{FirstSeen: stale, ProgressAt: now, PeersAt: stale}, problemNoPeers},
+		{"no progress", downloading(nil), torrentProgress{FirstSeen: stale, ProgressAt: stale, PeersAt: now}, problemNoProgress},
+		{"paused", downloading(func(t *transmission.Torrent) { t.Status = transmission.StatusStopped }), torrentProgress{FirstSeen: stale, ProgressAt: stale, PeersAt: stale}, ""},
+		{"seeding tracker error", downloading(func(t *transmission.Torrent) { t.PercentDone = 1; t.Error = torrentErrorTracker }), fresh, ""},
+		{"seeding local error", downloading(func(t *transmission.Torrent) { t.PercentDone = 1; t.Error = torrentErrorLocal }), fresh, problemError},
+	}
+	for _, c := range cases {
+		problem, ok := detectProblem(c.torrent, c.progress, now, timeout)
+		if problem.kind != c.want || ok != (c.want != "") {
+			t.Errorf("%s: got %q %v, want %q", c.name, problem.kind, ok, c.want)
+		}
+	}
+}
+
+func TestNotifierStateAlertsOnce(t *testing.T) {
+	now := time.Unix(100000, 0)
+	stale := now.Add(-3 * time.Hour)
+	state := &notifierState{Torrents: map[string]torrentProgress{
+		"stuck": {PercentDone: 0.03, FirstSeen: stale, ProgressAt: stale, PeersAt: now},
+	}}
+	torrents := transmission.TorrentMap{
+		"stuck": {Name: "Stuck", Status: transmission.StatusDownloading, PercentDone: 0.03, MetadataPercentComplete: 1, PeersConnected: 2},
+	}
+
+	due, ongoing := state.alerts(torrents, now, time.Hour)
+	if len(due) != 1 || due[0].problem.kind != problemNoProgress || len(ongoing) != 0 {
+		t.Fatalf("unexpected alerts %#v %v", due, ongoing)
+	}
+	if !strings.Contains(due[0].problem.description, "3h0m0s") {
+		t.Errorf("unexpected description %q", due[0].problem.description)
+	}
+
+	state.record(torrents, cycleResult{alerted: map[string]string{"stuck": problemNoProgress}}, now)
+	due, ongoing = state.alerts(torrents, now.Add(time.Minute), time.Hour)
+	if len(due) != 0 || ongoing["stuck"] != problemNoProgress {
+		t.Errorf("expected the problem not to be reported twice, got %#v %v", due, ongoing)
+	}
+}
+
+func TestParseStallTimeout(t *testing.T) {
+	if timeout, err := parseStallTimeout(""); err != nil || timeout != defaultStallTimeout {
+		t.Errorf("expected default, got %v %v", timeout, err)
+	}
+	if timeout, err := parseStallTimeout("90m"); err != nil || timeout != 90*time.Minute {
+		t.Errorf("unexpected %v %v", timeout, err)
+	}
+	for _, s := range []string{"soon", "-1h", "0s"} {
+		if _, err := parseStallTimeout(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
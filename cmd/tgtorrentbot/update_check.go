@@ -2,26 +2,55 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/minya/logger"
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
 	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/completionhooks"
+	"github.com/minya/tgtorrentbot/preferences"
 	"github.com/odwrtw/transmission"
 )
 
-func CreateCompletedCheckRoutine(transmissionClient *transmission.Client, api *telegram.Api) func() {
+// checkerOptions configures the update checker beyond completion notices.
+type checkerOptions struct {
+	// stallTimeout is how long a torrent may stall before its owner is alerted.
+	stallTimeout time.Duration
+	// digest is nil when the daily digest is disabled.
+	digest *digestSchedule
+	// hooks run for every announced completion; nil when none are configured.
+	hooks *completionhooks.Runner
+	// extractCategories are the categories whose archives are extracted on completion.
+	extractCategories []string
+	// extractor extracts them in the background; nil when none are configured.
+	extractor *extractor
+	// jellyfin is nil when Jellyfin is not configured.
+	jellyfin *jellyfinAnnouncer
+	// downloadPath is where the category folders are, to offer sending small downloads to the chat.
+	downloadPath string
+}
+
+// CreateCompletedCheckRoutine starts the completion notifier. It checks once at
+// startup, keeps polling while torrents are incomplete and resumes on notify.
+// Torrents that error or stall are reported to their owners, and the daily
+// digest is sent from the same polling. Messages go through the notifier so
+// that each user's notification settings apply.
+func CreateCompletedCheckRoutine(transmissionClient *transmission.Client, n *notifier, state *notifierState, opts checkerOptions) func() {
 	chanNotify := make(chan int, 1)
 
 	updateFn := func() {
-		var globalTorrentState transmission.TorrentMap
-		active := false
+		active := true
 		checkTorrents := func() {
-			newState, err := updateCheckRoutine(transmissionClient, api, globalTorrentState)
+			torrents, err := updateCheckRoutine(transmissionClient, n, state, opts)
 			if err == nil {
-				globalTorrentState = newState
-				if allCompleted(globalTorrentState) {
+				if now := time.Now(); opts.digest != nil && opts.digest.due(state, now) {
+					logger.Info("[UpdatesChecker] Sending daily digests")
+					opts.digest.sendDigests(n, torrents, state, now, opts.stallTimeout)
+				}
+				if allCompleted(torrents) {
 					logger.Info("[UpdatesChecker] All torrents completed. Update checking paused.")
 					active = false
 				}
@@ -33,6 +62,9 @@ func CreateCompletedCheckRoutine(transmissionClient *transmission.Client, api *t
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
+		logger.Info("[UpdatesChecker] Checking torrents at startup")
+		checkTorrents()
+
 		for {
 			select {
 			case <-chanNotify:
@@ -41,7 +73,7 @@ func CreateCompletedCheckRoutine(transmissionClient *transmission.Client, api *t
 				checkTorrents()
 
 			case <-ticker.C:
-				if active {
+				if active || (opts.digest != nil && opts.digest.due(state, time.Now())) {
 					checkTorrents()
 				}
 			}
@@ -63,42 +95,156 @@ func CreateCompletedCheckRoutine(transmissionClient *transmission.Client, api *t
 
 func updateCheckRoutine(
 	transmissionClient *transmission.Client,
-	api *telegram.Api,
-	state transmission.TorrentMap,
+	n *notifier,
+	state *notifierState,
+	opts checkerOptions,
 ) (transmission.TorrentMap, error) {
 	torrents, err := transmissionClient.GetTorrentMap()
 
 	if err != nil {
 		logger.Error(err, "[UpdatesChecker] Error getting torrent map")
-		return state, err
+		return nil, err
 	}
 
-	for hash, torrent := range torrents {
-		logger.Debug("[UpdatesChecker] Check torrent: %s", torrent.Name)
-		previous, ok := state[hash]
-		if !ok {
-			previous = torrent
+	polledAt := time.Now()
+	failed := map[string]bool{}
+	edited := map[string]string{}
+	for _, hash := range state.pendingCompletions(torrents) {
+		torrent := torrents[hash]
+		logger.Info("[UpdatesChecker] Found completed torrent: %s", torrent.Name)
+		text := commands.FormatTorrentCompleted(torrent)
+		extracting := opts.extractor != nil && slices.Contains(opts.extractCategories, getTorrentCategory(torrent))
+		if extracting {
+			text += "\n" + extractingNote
 		}
-		if torrent.PercentDone == 1 && previous.PercentDone < 1 {
-			chatID, err := getTorrentChatID(torrent)
-			if err != nil {
-				logger.Warn("[UpdatesChecker] No chat ID found for torrent %s, error: %v", torrent.Name, err)
-				continue
+
+		// Show the completion in the progress message, even for users who turned
+		// completion notices off, so it is not left stale
+		replyTo := int64(0)
+		if edit, ok := state.messageEdit(torrents, hash); ok {
+			replyTo = edit.messageID
+			if err := n.edit(edit.chatID, edit.messageID, edit.text, nil); err != nil {
+				logger.Warn("[UpdatesChecker] Error editing progress message of %s: %v", torrent.Name, err)
+			} else {
+				for _, h := range edit.hashes {
+					edited[h] = edit.text
+				}
 			}
+		}
 
-			logger.Info("[UpdatesChecker] Found completed torrent: %s", torrent.Name)
+		notice, err := sendCompletionNotice(n, torrent, text, replyTo, completionKeyboard(opts.downloadPath, torrent))
+		switch {
+		case botapi.IsPermanent(err):
+			logger.Warn("[UpdatesChecker] Completion notice for %s cannot be delivered, dropping it: %v", torrent.Name, err)
+		case err != nil:
+			logger.Error(err, "[UpdatesChecker] Error sending completion notice for %s, will retry", torrent.Name)
+			failed[hash] = true
+			continue
+		}
+		// Hooks and Jellyfin see the extracted files; extraction doesn't hold up
+		// the other torrents' notices, progress and alerts
+		announce := func(notice completionNotice) {
+			runCompletionHooks(n, opts.hooks, torrent)
+			opts.jellyfin.announce(n, torrent, notice)
+		}
+		if extracting {
+			opts.extractor.run(n, torrent, notice, announce)
+			continue
+		}
+		announce(notice)
+	}
+
+	for _, edit := range state.progressEdits(torrents, polledAt) {
+		if _, done := edited[edit.hashes[0]]; done {
+			continue
+		}
+		if err := n.edit(edit.chatID, edit.messageID, edit.text, nil); err != nil {
+			logger.Warn("[UpdatesChecker] Error editing progress message %d: %v", edit.messageID, err)
+			continue
+		}
+		for _, hash := range edit.hashes {
+			edited[hash] = edit.text
+		}
+	}
+
+	alerts, alerted := state.alerts(torrents, polledAt, opts.stallTimeout)
+	for _, alert := range alerts {
+		torrent := torrents[alert.hash]
+		chatID, err := getTorrentChatID(torrent)
+		if err != nil {
+			logger.Warn("[UpdatesChecker] No chat ID found for torrent %s, error: %v", torrent.Name, err)
+			continue
+		}
 
-			category := getTorrentCategory(torrent)
-			api.SendMessage(telegram.ReplyMessage{
-				ChatId: chatID,
-				Text:   fmt.Sprintf("Completed: %v [%s]", torrent.Name, category),
-			})
+		logger.Info("[UpdatesChecker] Torrent %s has a problem: %s", torrent.Name, alert.problem.description)
+		// An alert the owner turned off counts as delivered so it is not retried
+		_, err = n.send(chatID, preferences.EventStalled,
+			commands.FormatTorrentAlert(torrent, alert.problem.description),
+			commands.BuildTorrentAlertKeyboard(torrent))
+		if err != nil {
+			logger.Error(err, "[UpdatesChecker] Error sending alert for %s, will retry", torrent.Name)
+			continue
 		}
+		alerted[alert.hash] = alert.problem.kind
+	}
+
+	state.record(torrents, cycleResult{failed: failed, edited: edited, alerted: alerted}, polledAt)
+	if err := state.save(); err != nil {
+		logger.Error(err, "[UpdatesChecker] Error saving notifier state")
 	}
 
 	return torrents, nil
 }
 
+// completionNotice is the message that announced a completion; messageID is 0
+// when no message was sent.
+type completionNotice struct {
+	chatID    int64
+	messageID int64
+	text      string
+	// keyboard is nil when the notice has no buttons.
+	keyboard *telegram.InlineKeyboardMarkup
+}
+
+// completionKeyboard holds the buttons of a completion notice, nil when there are none.
+func completionKeyboard(downloadPath string, torrent *transmission.Torrent) *telegram.InlineKeyboardMarkup {
+	if downloadPath == "" {
+		return nil
+	}
+	buttons := sendToChatButtons(downloadPath, torrent)
+	if len(buttons) == 0 {
+		return nil
+	}
+	return &telegram.InlineKeyboardMarkup{InlineKeyboard: [][]telegram.InlineKeyboardButton{buttons}}
+}
+
+// sendCompletionNotice sends the completion notice to the torrent's owner, as a
+// reply to the progress message when there is one. Edits never notify, so the
+// notice is a new message even though the progress message shows it too.
+// Torrents added outside the bot have no owner and get no notice.
+func sendCompletionNotice(n *notifier, torrent *transmission.Torrent, text string, replyTo int64, keyboard *telegram.InlineKeyboardMarkup) (completionNotice, error) {
+	chatID, err := getTorrentChatID(torrent)
+	if err != nil {
+		logger.Warn("[UpdatesChecker] No chat ID found for torrent %s, error: %v", torrent.Name, err)
+		return completionNotice{}, nil
+	}
+
+	var markup any
+	if keyboard != nil {
+		markup = keyboard
+	}
+	var messageID int64
+	if replyTo != 0 {
+		messageID, err = n.reply(chatID, replyTo, preferences.EventCompleted, text, markup)
+	} else {
+		messageID, err = n.send(chatID, preferences.EventCompleted, text, markup)
+	}
+	if err != nil {
+		return completionNotice{}, err
+	}
+	return completionNotice{chatID: chatID, messageID: messageID, text: text, keyboard: keyboard}, nil
+}
+
 func getTorrentChatID(torrent *transmission.Torrent) (int64, error) {
 	if len(torrent.Labels) == 0 {
 		return 0, fmt.Errorf("no chatID in torrent's labels")
@@ -111,13 +257,12 @@ func getTorrentChatID(torrent *transmission.Torrent) (int64, error) {
 	return chatID, nil
 }
 
+// getTorrentCategory returns the category label, empty for torrents added outside the bot.
 func getTorrentCategory(torrent *transmission.Torrent) string {
-	if len(torrent.Labels) >= 2 {
-		if cat, ok := commands.ParseCategory(torrent.Labels[1]); ok {
-			return cat.DisplayName()
-		}
+	if len(torrent.Labels) < 2 {
+		return ""
 	}
-	return "Unknown"
+	return torrent.Labels[1]
 }
 
 func allCompleted(torrents transmission.TorrentMap) bool {
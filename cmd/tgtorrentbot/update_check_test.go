@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/commands"
+	"github.com/minya/tgtorrentbot/preferences"
+	"github.com/odwrtw/transmission"
+)
+
+// newTestTransmission serves torrent-get with the given torrents.
+func newTestTransmission(t *testing.T, torrents []map[string]any) *transmission.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"result":    "success",
+			"arguments": map[string]any{"torrents": torrents},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	client, err := transmission.New(transmission.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// botCall is a Bot API request received by the test server.
+type botCall struct {
+	method  string
+	payload map[string]any
+}
+
+// newTestBot records the Bot API calls and answers sendMessage with sendResponse.
+func newTestBot(t *testing.T, sendResponse string) (*botapi.Client, func() []botCall) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []botCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := botCall{method: r.URL.Path[len("/botTOKEN/"):]}
+		json.NewDecoder(r.Body).Decode(&call.payload)
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+		if call.method == "sendMessage" {
+			w.Write([]byte(sendResponse))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return botapi.NewWithBaseURL("TOKEN", srv.URL), func() []botCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func trackedState(hash string) *notifierState {
+	return &notifierState{
+		LastCheck: time.Unix(1000, 0),
+		Torrents: map[string]torrentProgress{
+			hash: {PercentDone: 0.5, ChatID: 42, MessageID: 10, TrackedAt: time.Unix(900, 0)},
+		},
+	}
+}
+
+func TestUpdateCheckRoutineCompletesTrackedTorrent(t *testing.T) {
+	client := newTestTransmission(t, []map[string]any{
+		{"hashString": "abc", "name": "Dune", "percentDone": 1, "labels": []string{"42", "movies"}},
+	})
+	bot, calls := newTestBot(t, `{"ok":true,"result":{"message_id":11}}`)
+	state := trackedState("abc")
+
+	torrents, err := updateCheckRoutine(client, newNotifier(bot, nil), state, checkerOptions{stallTimeout: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := commands.FormatTorrentCompleted(torrents["abc"])
+	got := calls()
+	if len(got) != 2 {
+		t.Fatalf("expected an edit and a notice, got %v", got)
+	}
+	edit, notice := got[0], got[1]
+	if edit.method != "editMessageText" || edit.payload["message_id"] != float64(10) || edit.payload["text"] != text {
+		t.Errorf("unexpected edit %v", edit)
+	}
+	replyTo, _ := notice.payload["reply_parameters"].(map[string]any)
+	if notice.method != "sendMessage" || notice.payload["chat_id"] != float64(42) || notice.payload["text"] != text ||
+		replyTo["message_id"] != float64(10) {
+		t.Errorf("expected the notice to reply to the progress message, got %v", notice)
+	}
+	if progress := state.Torrents["abc"]; !progress.Notified || progress.LastText != text {
+		t.Errorf("unexpected state %#v", progress)
+	}
+}
+
+func TestUpdateCheckRoutineEditsWhenNoticesAreOff(t *testing.T) {
+	client := newTestTransmission(t, []map[string]any{
+		{"hashString": "abc", "name": "Dune", "percentDone": 1, "labels": []string{"42", "movies"}},
+	})
+	bot, calls := newTestBot(t, `{"ok":true,"result":{"message_id":11}}`)
+	prefs, _ := preferences.Load("")
+	prefs.Update(42, func(p *preferences.Preferences) { p.Completed = false })
+	state := trackedState("abc")
+
+	if _, err := updateCheckRoutine(client, newNotifier(bot, prefs), state, checkerOptions{stallTimeout: time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := calls(); len(got) != 1 || got[0].method != "editMessageText" {
+		t.Errorf("expected only the progress message to be edited, got %v", got)
+	}
+	if !state.Torrents["abc"].Notified {
+		t.Error("expected a notice the user turned off to count as delivered")
+	}
+}
+
+func TestUpdateCheckRoutineDropsUndeliverableNotice(t *testing.T) {
+	client := newTestTransmission(t, []map[string]any{
+		{"hashString": "abc", "name": "Dune", "percentDone": 1, "labels": []string{"42", "movies"}},
+	})
+	bot, _ := newTestBot(t, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`)
+	state := trackedState("abc")
+
+	if _, err := updateCheckRoutine(client, newNotifier(bot, nil), state, checkerOptions{stallTimeout: time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !state.Torrents["abc"].Notified {
+		t.Error("expected a notice the user cannot receive not to be retried")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
+)
+
+// secretTokenHeader carries the webhook's secret token in Telegram's requests.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookListener is where the bot accepts updates from Telegram.
+type webhookListener struct {
+	address string
+	port    int
+	path    string
+	// certFile and keyFile serve the webhook over TLS when set.
+	certFile string
+	keyFile  string
+	// secret must match the header of every request.
+	secret string
+}
+
+// newWebhookSecret returns a random secret token for when none is configured;
+// the webhook is set on every start, so it only has to last the process.
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// setWebhook points Telegram at the listener. With TLS on, the listener's
+// certificate is uploaded so a self-signed one is accepted too.
+func setWebhook(bot *botapi.Client, url string, listener webhookListener) error {
+	webhook := botapi.Webhook{URL: url, SecretToken: listener.secret}
+	if listener.certFile != "" {
+		cert, err := os.Open(listener.certFile)
+		if err != nil {
+			return err
+		}
+		defer cert.Close()
+		webhook.Certificate = cert
+	}
+	return bot.SetWebhook(webhook)
+}
+
+// webhookDispatchTimeout is how long a webhook request waits for room in the
+// queue before Telegram is asked to deliver the update again.
+const webhookDispatchTimeout = 10 * time.Second
+
+// webhookHandler accepts updates posted by Telegram and rejects requests
+// without the webhook's secret token. An update that can't be queued is
+// answered with 503, so Telegram delivers it again later.
+func webhookHandler(secret string, dispatch func(context.Context, *telegram.Update) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(secret)) != 1 {
+			logger.Warn("Rejected webhook request without a valid secret token from %s", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var update telegram.Update
+		err := json.NewDecoder(r.Body).Decode(&update)
+
+		if err != nil {
+			logger.Error(err, "Failed to parse update from request")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), webhookDispatchTimeout)
+		defer cancel()
+		if err := dispatch(ctx, &update); err != nil {
+			logger.Warn("Update %d not queued, asking Telegram to retry: %v", update.UpdateId, err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// startListen serves the webhook until ctx is done, then stops accepting
+// requests and waits for those in progress.
+func startListen(ctx context.Context, listener webhookListener, dispatch func(context.Context, *telegram.Update) error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(listener.path, webhookHandler(listener.secret, dispatch))
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(listener.address, strconv.Itoa(listener.port)),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookDispatchTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Webhook listener did not stop cleanly: %v", err)
+		}
+	}()
+
+	var err error
+	if listener.certFile != "" {
+		logger.Info("Bot started, listening for updates on https://%s%s", srv.Addr, listener.path)
+		err = srv.ListenAndServeTLS(listener.certFile, listener.keyFile)
+	} else {
+		logger.Info("Bot started, listening for updates on http://%s%s", srv.Addr, listener.path)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "Server failed")
+		os.Exit(1)
+	}
+	<-stopped
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	handled := make(chan int64, 1)
+	handler := webhookHandler("s3cret", func(ctx context.Context, upd *telegram.Update) error {
+		handled <- upd.UpdateId
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		want   int
+	}{
+		{"no secret", http.MethodPost, "", http.StatusUnauthorized},
+		{"wrong secret", http.MethodPost, "guess", http.StatusUnauthorized},
+		{"not a post", http.MethodGet, "s3cret", http.StatusMethodNotAllowed},
+		{"valid", http.MethodPost, "s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", strings.NewReader(`{"update_id": 7}`))
+		if tt.secret != "" {
+			req.Header.Set(secretTokenHeader, tt.secret)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	select {
+	case id := <-handled:
+		if id != 7 {
+			t.Errorf("handled update %d, want 7", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the valid update was not handled")
+	}
+	select {
+	case id := <-handled:
+		t.Errorf("a rejected request was handled as update %d", id)
+	default:
+	}
+}
+
+func TestWebhookHandler_NotQueued(t *testing.T) {
+	handler := webhookHandler("s3cret", func(ctx context.Context, upd *telegram.Update) error {
+		return errDispatcherClosed
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"update_id": 7}`))
+	req.Header.Set(secretTokenHeader, "s3cret")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d so Telegram retries", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	a, b := newWebhookSecret(), newWebhookSecret()
+	if a == b || len(a) != 64 {
+		t.Errorf("expected distinct 64-character secrets, got %q and %q", a, b)
+	}
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/completionhooks"
+	"github.com/minya/tgtorrentbot/seeding"
+	"github.com/odwrtw/transmission"
+)
+
+// FormatTorrentAlert renders the message sent to a torrent's owner when it
+// errors or stalls.
+func FormatTorrentAlert(torrent *transmission.Torrent, problem string) string {
+	return fmt.Sprintf("⚠ %s [%s]\n%s\nProgress: %.1f%%, %d peers",
+		torrent.Name, torrentCategoryLabel(torrent), problem, torrent.PercentDone*100, torrent.PeersConnected)
+}
+
+// BuildTorrentAlertKeyboard offers the actions that usually revive a stalled torrent.
+func BuildTorrentAlertKeyboard(torrent *transmission.Torrent) telegram.InlineKeyboardMarkup {
+	action := func(text, name string) telegram.InlineKeyboardButton {
+		return telegram.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf("/ta %s %d", name, torrent.ID),
+		}
+	}
+
+	return telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{action("📣 Reannounce", actionReannounce), action("✔ Verify", actionVerify)},
+			{
+				{
+					Text:         "🗑 Remove",
+					CallbackData: fmt.Sprintf("/remove %d", torrent.ID),
+				},
+			},
+		},
+	}
+}
+
+// FormatHookFailures renders the report sent to a torrent's owner when some of
+// its completion hooks fail.
+func FormatHookFailures(torrent *transmission.Torrent, failures []completionhooks.Failure) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚠ Post-processing failed for %s [%s]", torrent.Name, torrentCategoryLabel(torrent))
+	for _, failure := range failures {
+		fmt.Fprintf(&sb, "\n• %s: %v", failure.Hook, failure.Err)
+	}
+	return sb.String()
+}
+
+// FormatSeedingAction renders the message sent to a torrent's owner when its
+// category's seeding policy stopped or removed it.
+func FormatSeedingAction(torrent *transmission.Torrent, decision seeding.Decision) string {
+	if decision.Action == seeding.ActionRemove {
+		return fmt.Sprintf("🧹 Removed %s [%s] from Transmission: %s, ratio %.2f\nThe downloaded files were kept.",
+			torrent.Name, torrentCategoryLabel(torrent), decision.Reason, max(torrent.UploadRatio, 0))
+	}
+	return fmt.Sprintf("⏹ Stopped seeding %s [%s]: %s, ratio %.2f\nUse /resume %d to seed again.",
+		torrent.Name, torrentCategoryLabel(torrent), decision.Reason, max(torrent.UploadRatio, 0), torrent.ID)
+}
@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/bencode"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/preferences"
+)
+
+const (
+	// DefaultUploadBatchWindow is how long to wait for more documents from the
+	// same chat before prompting for a category.
+	DefaultUploadBatchWindow = 3 * time.Second
+	fileBatchTTL             = time.Hour
+	maxPendingUpload         = 256
+)
+
+// batchFile is an uploaded document together with its parse result.
+type batchFile struct {
+	FileID   string
+	FileName string
+	Torrent  bencode.Torrent
+	Err      error
+}
+
+func (f batchFile) displayName() string {
+	if f.Err == nil {
+		return f.Torrent.Name
+	}
+	return f.FileName
+}
+
+type fileBatch struct {
+	files []batchFile
+	timer *time.Timer
+	flush func([]batchFile)
+}
+
+// fileBatcher groups documents that arrive close together from the same chat.
+type fileBatcher struct {
+	mu      sync.Mutex
+	pending map[int64]*fileBatch
+	// flushing counts the batches not yet flushed, so Flush can wait for the
+	// ones whose timer already fired.
+	flushing sync.WaitGroup
+}
+
+func newFileBatcher() *fileBatcher {
+	return &fileBatcher{pending: make(map[int64]*fileBatch)}
+}
+
+// Add appends a file to the chat's batch and restarts its timer; flush is
+// called with all files of the batch once the chat has been quiet for the
+// window. Without a window every file is flushed on its own right away.
+func (b *fileBatcher) Add(chatID int64, file batchFile, window time.Duration, flush func([]batchFile)) {
+	if window <= 0 {
+		flush([]batchFile{file})
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.pending[chatID]
+	if !ok {
+		batch = &fileBatch{}
+		batch.timer = time.AfterFunc(window, func() { b.fire(chatID) })
+		b.pending[chatID] = batch
+		b.flushing.Add(1)
+	} else {
+		batch.timer.Reset(window)
+	}
+	batch.files = append(batch.files, file)
+	batch.flush = flush
+}
+
+func (b *fileBatcher) fire(chatID int64) {
+	b.mu.Lock()
+	batch, ok := b.pending[chatID]
+	delete(b.pending, chatID)
+	b.mu.Unlock()
+
+	if ok {
+		batch.flush(batch.files)
+		b.flushing.Done()
+	}
+}
+
+// Flush flushes every pending batch without waiting for its window, and
+// returns once all of them are flushed.
+func (b *fileBatcher) Flush() {
+	b.mu.Lock()
+	var batches []*fileBatch
+	for chatID, batch := range b.pending {
+		// A timer that already fired flushes its batch itself
+		if batch.timer.Stop() {
+			batches = append(batches, batch)
+			delete(b.pending, chatID)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, batch := range batches {
+		batch.flush(batch.files)
+		b.flushing.Done()
+	}
+	b.flushing.Wait()
+}
+
+// FlushUploadBatches prompts for the documents still waiting for their batch
+// window; call it on shutdown, after no more updates are handled.
+func FlushUploadBatches() {
+	uploadBatcher.Flush()
+}
+
+// ParseUploadBatchWindow reads the upload batch window setting; empty means the
+// default and zero turns batching off.
+func ParseUploadBatchWindow(s string) (time.Duration, error) {
+	if s == "" {
+		return DefaultUploadBatchWindow, nil
+	}
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upload batch window %q: %w", s, err)
+	}
+	if window < 0 {
+		return 0, fmt.Errorf("upload batch window must not be negative, got %s", s)
+	}
+	return window, nil
+}
+
+var (
+	uploadBatcher = newFileBatcher()
+	// uploadBatches keeps the valid files of a batch until a category is chosen.
+	uploadBatches = newTokenStore[[]batchFile](fileBatchTTL, maxPendingUpload)
+)
+
+// sendFileBatchPrompt asks for a category for a batch of uploaded documents; a
+// batch of one keeps the single-file preview.
+func sendFileBatchPrompt(env environment.Env, chatID int64, files []batchFile) {
+	if len(files) == 1 {
+		file := files[0]
+		if file.Err != nil {
+			env.TgApi.SendMessage(telegram.ReplyMessage{
+				ChatId: chatID,
+				Text:   fmt.Sprintf("This file is not a valid torrent: %v", file.Err),
+			})
+			return
+		}
+		env.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId:      chatID,
+			Text:        formatTorrentPreview(file.Torrent) + "\n\nSelect category:",
+			ReplyMarkup: buildCategoryKeyboard("/dlfilecat", file.FileID),
+		})
+		return
+	}
+
+	var valid []batchFile
+	for _, file := range files {
+		if file.Err == nil {
+			valid = append(valid, file)
+		}
+	}
+
+	text := formatBatchSummary(files)
+	if len(valid) == 0 {
+		env.TgApi.SendMessage(telegram.ReplyMessage{ChatId: chatID, Text: text})
+		return
+	}
+
+	token, err := uploadBatches.Put(valid)
+	if err != nil {
+		logger.Error(err, "Error storing upload batch")
+		return
+	}
+	env.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId:      chatID,
+		Text:        text + fmt.Sprintf("\n\nSelect category for %d torrents:", len(valid)),
+		ReplyMarkup: buildCategoryKeyboard("/dlbatch", token),
+	})
+}
+
+// formatBatchSummary lists every document of a batch with its size or the reason it was rejected.
+func formatBatchSummary(files []batchFile) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Received %d files:", len(files)))
+	for i, file := range files {
+		if file.Err != nil {
+			sb.WriteString(fmt.Sprintf("\n%d. ✗ %s: not a valid torrent (%v)", i+1, file.displayName(), file.Err))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. %s — %s", i+1, file.Torrent.Name, formatBytes(file.Torrent.TotalSize)))
+		if n := len(file.Torrent.Files); n > 1 {
+			sb.WriteString(fmt.Sprintf(", %d files", n))
+		}
+	}
+	return sb.String()
+}
+
+// DownloadBatchWithCategoryCommand adds every torrent of a pending batch with the chosen category
+type DownloadBatchWithCategoryCommand struct {
+	Token    string
+	Category Category
+	environment.Env
+}
+
+type DownloadBatchWithCategoryCommandFactory struct {
+	environment.Env
+}
+
+var reDownloadBatchWithCategoryCmd = regexp.MustCompile(`^/dlbatch\s+(\S+)\s+([0-9a-f]+)$`)
+
+func (factory *DownloadBatchWithCategoryCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	if found := reDownloadBatchWithCategoryCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 3 {
+		categoryStr := strings.TrimSpace(found[1])
+
+		category, ok := ParseCategory(categoryStr)
+		if !ok {
+			logger.Error(nil, "Invalid category: %s", categoryStr)
+			return false, nil
+		}
+
+		return true, &DownloadBatchWithCategoryCommand{
+			Token:    found[2],
+			Category: category,
+			Env:      factory.Env,
+		}
+	}
+	return false, nil
+}
+
+// batchResult is the outcome of adding one torrent of a batch.
+type batchResult struct {
+	Name      string
+	TorrentID int
+	Hash      string
+	Err       error
+}
+
+func (cmd *DownloadBatchWithCategoryCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+
+	files, ok := uploadBatches.Get(cmd.Token)
+	if !ok {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "These files expired, please send them again",
+		})
+		return nil
+	}
+
+	results := make([]batchResult, 0, len(files))
+	for _, file := range files {
+		result := batchResult{Name: file.Torrent.Name}
+		content, err := cmd.downloadDocument(file.FileID)
+		if err == nil {
+			torrent, addErr := addTorrent(cmd.Env, metainfoArg(content), chatID, cmd.Category)
+			if addErr == nil {
+				result.TorrentID = torrent.ID
+				result.Hash = torrent.HashString
+			}
+			err = addErr
+		}
+		result.Err = err
+		results = append(results, result)
+	}
+
+	// Failures are always reported; a batch that was added in full follows the
+	// user's choice for added notices like a single torrent does
+	send, silent := cmd.Preferences.Delivery(chatID, preferences.EventAdded, time.Now())
+	if !send && !slices.ContainsFunc(results, func(r batchResult) bool { return r.Err != nil }) {
+		return nil
+	}
+
+	messageID, err := cmd.BotAPI.SendMessage(botapi.Message{
+		ChatID:              chatID,
+		Text:                formatBatchResults(results, cmd.Category),
+		DisableNotification: silent,
+	})
+	if err != nil {
+		logger.Error(err, "Error sending batch results")
+		return nil
+	}
+
+	// The notifier keeps editing the summary with the progress of the added torrents
+	if cmd.Progress != nil {
+		for _, r := range results {
+			if r.Err == nil {
+				cmd.Progress.TrackProgress(r.Hash, chatID, messageID)
+			}
+		}
+	}
+	return nil
+}
+
+func (cmd *DownloadBatchWithCategoryCommand) downloadDocument(fileID string) ([]byte, error) {
+	file, err := cmd.TgApi.GetFile(fileID)
+	if err != nil {
+		logger.Error(err, "Error getting file")
+		return nil, err
+	}
+	content, err := cmd.TgApi.DownloadFile(file)
+	if err != nil {
+		logger.Error(err, "Error downloading file")
+		return nil, err
+	}
+	return content, nil
+}
+
+// formatBatchResults reports per-file success or failure of a batch in one message.
+func formatBatchResults(results []batchResult, category Category) string {
+	added := 0
+	for _, r := range results {
+		if r.Err == nil {
+			added++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Added %d of %d torrents [%s]:", added, len(results), category.DisplayName()))
+	for _, r := range results {
+		if r.Err != nil {
+			sb.WriteString(fmt.Sprintf("\n✗ %s: %v", r.Name, r.Err))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n✓ %v %s", r.TorrentID, r.Name))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/bencode"
+)
+
+func TestFileBatcherGroupsByChat(t *testing.T) {
+	batcher := newFileBatcher()
+	window := 50 * time.Millisecond
+	flushed := make(chan []batchFile, 2)
+	flush := func(files []batchFile) { flushed <- files }
+
+	batcher.Add(1, batchFile{FileID: "a"}, window, flush)
+	batcher.Add(2, batchFile{FileID: "c"}, window, flush)
+	time.Sleep(20 * time.Millisecond)
+	batcher.Add(1, batchFile{FileID: "b"}, window, flush)
+
+	got := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case files := <-flushed:
+			var ids []string
+			for _, f := range files {
+				ids = append(ids, f.FileID)
+			}
+			got[strings.Join(ids, ",")]++
+		case <-time.After(time.Second):
+			t.Fatal("batch was not flushed")
+		}
+	}
+	if got["a,b"] != 1 || got["c"] != 1 {
+		t.Errorf("unexpected batches %v", got)
+	}
+}
+
+func TestFileBatcherWithoutWindow(t *testing.T) {
+	batcher := newFileBatcher()
+	var flushed [][]batchFile
+	flush := func(files []batchFile) { flushed = append(flushed, files) }
+
+	batcher.Add(1, batchFile{FileID: "a"}, 0, flush)
+	batcher.Add(1, batchFile{FileID: "b"}, 0, flush)
+
+	if len(flushed) != 2 || len(flushed[0]) != 1 || len(flushed[1]) != 1 {
+		t.Errorf("expected each file to be flushed on its own right away, got %v", flushed)
+	}
+}
+
+func TestFileBatcherFlush(t *testing.T) {
+	batcher := newFileBatcher()
+	var flushed []string
+	flush := func(files []batchFile) { flushed = append(flushed, files[0].FileID) }
+
+	batcher.Add(1, batchFile{FileID: "a"}, time.Hour, flush)
+	batcher.Add(2, batchFile{FileID: "b"}, time.Hour, flush)
+	batcher.Flush()
+
+	if len(flushed) != 2 {
+		t.Errorf("expected both pending batches to be flushed on shutdown, got %v", flushed)
+	}
+	if len(batcher.pending) != 0 {
+		t.Errorf("expected no pending batches, got %d", len(batcher.pending))
+	}
+}
+
+func TestParseUploadBatchWindow(t *testing.T) {
+	for input, want := range map[string]time.Duration{"": DefaultUploadBatchWindow, "0": 0, "1500ms": 1500 * time.Millisecond} {
+		if got, err := ParseUploadBatchWindow(input); err != nil || got != want {
+			t.Errorf("ParseUploadBatchWindow(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"soon", "-1s"} {
+		if _, err := ParseUploadBatchWindow(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestFormatBatchSummary(t *testing.T) {
+	files := []batchFile{
+		{FileName: "a.torrent", Torrent: bencode.Torrent{Name: "Movie", TotalSize: 1536 * 1024 * 1024, Files: make([]bencode.File, 1)}},
+		{FileName: "notes.txt", Err: errors.New("missing info dictionary")},
+		{FileName: "b.torrent", Torrent: bencode.Torrent{Name: "Show", TotalSize: 1024, Files: make([]bencode.File, 3)}},
+	}
+
+	text := formatBatchSummary(files)
+	for _, want := range []string{
+		"Received 3 files:",
+		"1. Movie — 1.5 GB",
+		"2. ✗ notes.txt: not a valid torrent (missing info dictionary)",
+		"3. Show — 1.0 KB, 3 files",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in %q", want, text)
+		}
+	}
+}
+
+func TestFormatBatchResults(t *testing.T) {
+	results := []batchResult{
+		{Name: "Movie", TorrentID: 12},
+		{Name: "Show", Err: errors.New("duplicate torrent")},
+	}
+
+	text := formatBatchResults(results, CategoryMovies)
+	for _, want := range []string{"Added 1 of 2 torrents", "✓ 12 Movie", "✗ Show: duplicate torrent"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in %q", want, text)
+		}
+	}
+}
+
+func TestDownloadBatchWithCategoryCommandFactory(t *testing.T) {
+	factory := DownloadBatchWithCategoryCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/dlbatch movies 0a1b2c3d"}})
+	if !ok {
+		t.Fatal("expected factory to accept batch category callback")
+	}
+	if batchCmd := cmd.(*DownloadBatchWithCategoryCommand); batchCmd.Token != "0a1b2c3d" || batchCmd.Category != CategoryMovies {
+		t.Errorf("unexpected command %#v", batchCmd)
+	}
+}
@@ -1,7 +1,10 @@
 package commands
 
 import (
+	"slices"
+
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
 )
 
 type CommandFactory interface {
@@ -22,6 +25,39 @@ func AnswerCallbackQuery(upd *telegram.Update, api *telegram.Api) {
 	}
 }
 
+// commandText returns the message text or the callback data of an update,
+// for commands that can be both typed and triggered from a button.
+func commandText(upd *telegram.Update) string {
+	switch {
+	case upd.Message != nil:
+		return upd.Message.Text
+	case upd.CallbackQuery != nil:
+		return upd.CallbackQuery.Data
+	}
+	return ""
+}
+
+// replyChatID answers the callback query, if any, and returns the chat to reply to.
+func replyChatID(upd *telegram.Update, api *telegram.Api) int64 {
+	if upd.CallbackQuery != nil {
+		AnswerCallbackQuery(upd, api)
+		return upd.CallbackQuery.Message.Chat.Id
+	}
+	return upd.Message.Chat.Id
+}
+
+// isAdmin reports whether the update comes from one of the configured admin users.
+func isAdmin(env environment.Env, upd *telegram.Update) bool {
+	var user *telegram.User
+	switch {
+	case upd.Message != nil:
+		user = upd.Message.From
+	case upd.CallbackQuery != nil:
+		user = upd.CallbackQuery.From
+	}
+	return user != nil && slices.Contains(env.AdminUsers, user.Id)
+}
+
 // func ParseCommand(cmdText string) (ok bool, cmd any) {
 // 	reListCmd := regexp.MustCompile(`^/list\s*?$`)
 // 	reRemCmd := regexp.MustCompile(`^/remove\s(\d+)\s*?$`)
@@ -1,9 +1,13 @@
 package commands
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/minya/rutracker"
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/bencode"
 )
 
 func TestSearchCommandFactoryAcceptsSlashSearch(t *testing.T) {
@@ -147,3 +151,123 @@ func TestDownloadByFileCommandFactoryRejectsWithoutDocument(t *testing.T) {
 		t.Fatalf("expected factory to reject update without document")
 	}
 }
+
+func TestSearchPageCommandFactoryAcceptsCallback(t *testing.T) {
+	factory := SearchPageCommandFactory{}
+	upd := telegram.Update{
+		CallbackQuery: &telegram.CallbackQuery{
+			Data: "/search_page 0a1b2c3d 2",
+		},
+	}
+
+	ok, cmd := factory.Accepts(&upd)
+	if !ok {
+		t.Fatalf("expected factory to accept search page callback")
+	}
+
+	pageCmd, ok := cmd.(*SearchPageCommand)
+	if !ok {
+		t.Fatalf("expected *SearchPageCommand, got %T", cmd)
+	}
+	if pageCmd.Token != "0a1b2c3d" || pageCmd.Page != 2 {
+		t.Fatalf("unexpected token/page: %q/%d", pageCmd.Token, pageCmd.Page)
+	}
+}
+
+func TestSearchDownloadCommandFactoryResolvesSessionItem(t *testing.T) {
+	token, err := searchSessions.Put(&searchSession{Query: "query", Items: []rutracker.RutrackerSearchItem{
+		{Title: "first", DownloadURL: "dl.php?t=1"},
+		{Title: "second", DownloadURL: "dl.php?t=2"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	factory := SearchDownloadCommandFactory{}
+	upd := telegram.Update{
+		CallbackQuery: &telegram.CallbackQuery{
+			Data: "/sdl " + token + " 1",
+		},
+	}
+
+	ok, cmd := factory.Accepts(&upd)
+	if !ok {
+		t.Fatalf("expected factory to accept search download callback")
+	}
+	downloadCmd, ok := cmd.(*DownloadCommand)
+	if !ok {
+		t.Fatalf("expected *DownloadCommand, got %T", cmd)
+	}
+	if downloadCmd.URL != "dl.php?t=2" {
+		t.Fatalf("expected URL dl.php?t=2, got %q", downloadCmd.URL)
+	}
+}
+
+func TestSearchDownloadCommandFactoryUnknownToken(t *testing.T) {
+	factory := SearchDownloadCommandFactory{}
+	upd := telegram.Update{
+		CallbackQuery: &telegram.CallbackQuery{
+			Data: "/sdl ffffffff 0",
+		},
+	}
+
+	ok, cmd := factory.Accepts(&upd)
+	if !ok {
+		t.Fatalf("expected factory to accept search download callback")
+	}
+	if _, ok := cmd.(*SearchExpiredCommand); !ok {
+		t.Fatalf("expected *SearchExpiredCommand, got %T", cmd)
+	}
+}
+
+func TestPrepareSearchResultsPage(t *testing.T) {
+	items := make([]rutracker.RutrackerSearchItem, 12)
+	for i := range items {
+		items[i] = rutracker.RutrackerSearchItem{Title: fmt.Sprintf("item %d", i+1), DownloadURL: fmt.Sprintf("dl.php?t=%d", i)}
+	}
+	session := &searchSession{Query: "q", Items: items}
+
+	text, keyboard := prepareSearchResultsPage(session, "abcd1234", 2)
+	if !strings.Contains(text, "11. item 11") || !strings.Contains(text, "12. item 12") {
+		t.Fatalf("expected last page to contain items 11 and 12, got %q", text)
+	}
+	if !strings.Contains(text, "Page 3/3 (total: 12)") {
+		t.Fatalf("unexpected page footer in %q", text)
+	}
+
+	if len(keyboard.InlineKeyboard) != 2 {
+		t.Fatalf("expected download and navigation rows, got %d rows", len(keyboard.InlineKeyboard))
+	}
+	downloadRow := keyboard.InlineKeyboard[0]
+	if len(downloadRow) != 2 || downloadRow[0].CallbackData != "/sdl abcd1234 10" {
+		t.Fatalf("unexpected download row: %#v", downloadRow)
+	}
+	for _, button := range downloadRow {
+		if len(button.CallbackData) > 64 {
+			t.Fatalf("callback data exceeds Telegram limit: %q", button.CallbackData)
+		}
+	}
+	navRow := keyboard.InlineKeyboard[1]
+	if len(navRow) != 1 || navRow[0].CallbackData != "/search_page abcd1234 1" {
+		t.Fatalf("unexpected navigation row: %#v", navRow)
+	}
+}
+
+func TestFormatTorrentPreview(t *testing.T) {
+	torrent := bencode.Torrent{
+		Name:     "Show",
+		InfoHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		Files: []bencode.File{
+			{Path: "a.srt", Length: 1024},
+			{Path: "b.mkv", Length: 2 * 1024 * 1024 * 1024},
+		},
+		TotalSize: 2*1024*1024*1024 + 1024,
+	}
+
+	text := formatTorrentPreview(torrent)
+	for _, want := range []string{"Show\n", "in 2 files", "Infohash: c12fe1c06bba254a9dc9f519b335aa7c1367a88a", "Largest files:\n• b.mkv (2.0 GB)\n• a.srt"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in preview %q", want, text)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/preferences"
+	"github.com/odwrtw/transmission"
+)
+
+// digestWindow is how far back the digest looks for finished torrents.
+const digestWindow = 24 * time.Hour
+
+// DigestCommand shows or changes the daily digest subscription: /digest [on|off]
+type DigestCommand struct {
+	Enable *bool
+	environment.Env
+}
+
+type DigestCommandFactory struct {
+	environment.Env
+}
+
+var reDigestCmd = regexp.MustCompile(`^/digest(?:\s+(on|off))?\s*$`)
+
+func (factory *DigestCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.Message == nil {
+		return false, nil
+	}
+	found := reDigestCmd.FindStringSubmatch(upd.Message.Text)
+	if found == nil {
+		return false, nil
+	}
+	cmd := &DigestCommand{Env: factory.Env}
+	if found[1] != "" {
+		enable := found[1] == "on"
+		cmd.Enable = &enable
+	}
+	return true, cmd
+}
+
+func (cmd *DigestCommand) Handle(upd *telegram.Update) error {
+	chatID := upd.Message.Chat.Id
+	reply := func(text string) {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{ChatId: chatID, Text: text})
+	}
+
+	if cmd.Preferences == nil || cmd.DigestTime == "" {
+		reply("The daily digest is not configured on this bot")
+		return nil
+	}
+	if upd.Message.From == nil {
+		return nil
+	}
+	userID := upd.Message.From.Id
+
+	prefs := cmd.Preferences.Get(userID)
+	if cmd.Enable != nil {
+		var err error
+		prefs, err = cmd.Preferences.Update(userID, func(p *preferences.Preferences) { p.Digest = *cmd.Enable })
+		if err != nil {
+			logger.Error(err, "Error saving preferences")
+			reply("Failed to save your preference, please try again")
+			return err
+		}
+	}
+
+	if prefs.Digest {
+		reply(fmt.Sprintf("Daily digest is on, it is sent every day at %s. Turn it off with /digest off", cmd.DigestTime))
+	} else {
+		reply(fmt.Sprintf("Daily digest is off. Turn it on with /digest on to get a summary every day at %s", cmd.DigestTime))
+	}
+	return nil
+}
+
+// DigestReport is the daily summary of one user's torrents.
+type DigestReport struct {
+	Finished    []*transmission.Torrent
+	Downloading []*transmission.Torrent
+	Stalled     []DigestProblem
+	DiskUsage   []CategoryUsage
+}
+
+// DigestProblem is a torrent with an error or stall.
+type DigestProblem struct {
+	Torrent     *transmission.Torrent
+	Description string
+}
+
+// CategoryUsage is the disk space used by a category directory.
+type CategoryUsage struct {
+	Category Category
+	Bytes    int64
+}
+
+// BuildDigestReport selects the chat's torrents that finished within the last
+// day, are still downloading or have problems, keyed by hash in problems.
+func BuildDigestReport(torrents []*transmission.Torrent, chatID int64, now time.Time, problems map[string]string, usage []CategoryUsage) DigestReport {
+	report := DigestReport{DiskUsage: usage}
+	for _, torrent := range ownedTorrents(torrents, chatID) {
+		if problem, ok := problems[torrent.HashString]; ok {
+			report.Stalled = append(report.Stalled, DigestProblem{Torrent: torrent, Description: problem})
+		}
+		switch {
+		case torrent.PercentDone < 1:
+			report.Downloading = append(report.Downloading, torrent)
+		case now.Sub(time.Unix(int64(torrent.DoneDate), 0)) <= digestWindow:
+			report.Finished = append(report.Finished, torrent)
+		}
+	}
+	sort.Slice(report.Finished, func(i, j int) bool { return report.Finished[i].DoneDate < report.Finished[j].DoneDate })
+	sort.Slice(report.Downloading, func(i, j int) bool { return report.Downloading[i].ID < report.Downloading[j].ID })
+	return report
+}
+
+// FormatDigest renders a digest report.
+func FormatDigest(report DigestReport) string {
+	var sb strings.Builder
+	sb.WriteString("Daily digest\n")
+
+	sb.WriteString("\nFinished in the last 24h:")
+	if len(report.Finished) == 0 {
+		sb.WriteString(" nothing")
+	}
+	for _, torrent := range report.Finished {
+		fmt.Fprintf(&sb, "\n✓ %s [%s]", torrent.Name, torrentCategoryLabel(torrent))
+	}
+
+	sb.WriteString("\n\nDownloading:")
+	if len(report.Downloading) == 0 {
+		sb.WriteString(" nothing")
+	}
+	for _, torrent := range report.Downloading {
+		fmt.Fprintf(&sb, "\n%d %s %.0f%%, ETA %s", torrent.ID, torrent.Name, torrent.PercentDone*100, formatETA(torrent.Eta))
+	}
+
+	if len(report.Stalled) > 0 {
+		sb.WriteString("\n\nNeeds attention:")
+		for _, problem := range report.Stalled {
+			fmt.Fprintf(&sb, "\n⚠ %d %s: %s", problem.Torrent.ID, problem.Torrent.Name, problem.Description)
+		}
+	}
+
+	if len(report.DiskUsage) > 0 {
+		sb.WriteString("\n\nDisk usage:")
+		for _, usage := range report.DiskUsage {
+			fmt.Fprintf(&sb, "\n%s: %s", usage.Category.DisplayName(), formatBytes(usage.Bytes))
+		}
+	}
+	return sb.String()
+}
+
+// CategoryDiskUsage sums the sizes of the files in every category directory
+// under the download path; missing directories are skipped.
+func CategoryDiskUsage(downloadPath string) []CategoryUsage {
+	var usage []CategoryUsage
+	for _, category := range AllCategories() {
+		dir := filepath.Join(downloadPath, category.String())
+		var total int64
+		found := false
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			found = true
+			if d.Type().IsRegular() {
+				if info, err := d.Info(); err == nil {
+					total += info.Size()
+				}
+			}
+			return nil
+		})
+		if err != nil && !found {
+			continue
+		}
+		if err != nil {
+			logger.Warn("Error walking %s: %v", dir, err)
+		}
+		usage = append(usage, CategoryUsage{Category: category, Bytes: total})
+	}
+	return usage
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minya/telegram"
+	"github.com/odwrtw/transmission"
+)
+
+func TestDigestCommandFactory(t *testing.T) {
+	factory := DigestCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/digest on"}})
+	if !ok || cmd.(*DigestCommand).Enable == nil || !*cmd.(*DigestCommand).Enable {
+		t.Errorf("expected /digest on, got %v %#v", ok, cmd)
+	}
+	ok, cmd = factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/digest"}})
+	if !ok || cmd.(*DigestCommand).Enable != nil {
+		t.Errorf("expected status request, got %v %#v", ok, cmd)
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/digest maybe"}}); ok {
+		t.Error("expected factory to reject unknown argument")
+	}
+}
+
+func TestBuildDigestReport(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	torrents := []*transmission.Torrent{
+		{ID: 1, Name: "Recent", HashString: "a", PercentDone: 1, DoneDate: int(now.Add(-time.Hour).Unix()), Labels: []string{"111", "movies"}},
+		{ID: 2, Name: "Old", HashString: "b", PercentDone: 1, DoneDate: int(now.Add(-48 * time.Hour).Unix()), Labels: []string{"111", "movies"}},
+		{ID: 3, Name: "Stuck", HashString: "c", PercentDone: 0.03, Eta: -1, Labels: []string{"111", "shows"}},
+		{ID: 4, Name: "Theirs", HashString: "d", PercentDone: 0.5, Labels: []string{"222", "shows"}},
+	}
+	usage := []CategoryUsage{{Category: CategoryMovies, Bytes: 3 << 30}}
+
+	report := BuildDigestReport(torrents, 111, now, map[string]string{"c": "No peers for 3h0m0s", "d": "No progress"}, usage)
+	if len(report.Finished) != 1 || report.Finished[0].ID != 1 {
+		t.Errorf("unexpected finished %v", report.Finished)
+	}
+	if len(report.Downloading) != 1 || len(report.Stalled) != 1 || report.Stalled[0].Torrent.ID != 3 {
+		t.Errorf("unexpected report %#v", report)
+	}
+
+	text := FormatDigest(report)
+	for _, want := range []string{"✓ Recent [Movies]", "3 Stuck 3%, ETA unknown", "⚠ 3 Stuck: No peers for 3h0m0s", "Movies: 3.0 GB"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in %q", want, text)
+		}
+	}
+	if strings.Contains(text, "Theirs") || strings.Contains(text, "Old") {
+		t.Errorf("unexpected torrents in %q", text)
+	}
+}
+
+func TestCategoryDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "movies", "Film"), 0o755)
+	os.WriteFile(filepath.Join(root, "movies", "Film", "a.mkv"), make([]byte, 100), 0o644)
+	os.WriteFile(filepath.Join(root, "movies", "b.mkv"), make([]byte, 50), 0o644)
+
+	usage := CategoryDiskUsage(root)
+	if len(usage) != 1 || usage[0].Category != CategoryMovies || usage[0].Bytes != 150 {
+		t.Errorf("unexpected usage %#v", usage)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/minya/logger"
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/botapi"
 	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/preferences"
 	"github.com/odwrtw/transmission"
 )
 
@@ -50,12 +53,17 @@ func (cmd *DownloadCommand) Handle(upd *telegram.Update) error {
 }
 
 func (cmd *DownloadCommand) buildCategoryKeyboard() telegram.InlineKeyboardMarkup {
+	return buildCategoryKeyboard("/dlcat", cmd.URL)
+}
+
+// buildCategoryKeyboard returns one button per category with "<command> <category> <arg>" callback data.
+func buildCategoryKeyboard(command string, arg string) telegram.InlineKeyboardMarkup {
 	var buttons [][]telegram.InlineKeyboardButton
 
 	for _, cat := range AllCategories() {
 		button := telegram.InlineKeyboardButton{
 			Text:         cat.DisplayName(),
-			CallbackData: fmt.Sprintf("/dlcat %s %s", cat.String(), cmd.URL),
+			CallbackData: fmt.Sprintf("%s %s %s", command, cat.String(), arg),
 		}
 		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
 	}
@@ -66,20 +74,55 @@ func (cmd *DownloadCommand) buildCategoryKeyboard() telegram.InlineKeyboardMarku
 }
 
 func (cmd *DownloadCommand) addTorrentAndReply(content []byte, chatID int64, category Category) error {
-	torrentBase64 := base64.StdEncoding.EncodeToString(content)
+	return addTorrentArgAndReply(cmd.Env, metainfoArg(content), chatID, category)
+}
 
-	downloadDir := fmt.Sprintf("%s/%s", cmd.DownloadPath, category.String())
+// metainfoArg wraps raw .torrent content for the transmission RPC.
+func metainfoArg(content []byte) transmission.AddTorrentArg {
+	return transmission.AddTorrentArg{Metainfo: base64.StdEncoding.EncodeToString(content)}
+}
+
+// addTorrentArgAndReply adds a torrent (metainfo or magnet/URL filename) into the
+// category directory, labels it with the chat and category and reports back.
+func addTorrentArgAndReply(env environment.Env, arg transmission.AddTorrentArg, chatID int64, category Category) error {
+	torrent, err := addTorrent(env, arg, chatID, category)
+	if err != nil {
+		return err
+	}
 
-	logger.Debug("Adding torrent with category %s to directory %s", category.String(), downloadDir)
+	send, silent := env.Preferences.Delivery(chatID, preferences.EventAdded, time.Now())
+	if !send {
+		return nil
+	}
 
-	torrent, err := cmd.TransmissionClient.AddTorrent(transmission.AddTorrentArg{
-		Metainfo:    torrentBase64,
-		DownloadDir: downloadDir,
+	messageID, err := env.BotAPI.SendMessage(botapi.Message{
+		ChatID:              chatID,
+		Text:                fmt.Sprintf("Added: %v [%s]", torrent.ID, category.DisplayName()),
+		DisableNotification: silent,
 	})
+	if err != nil {
+		logger.Error(err, "Error sending added message")
+		return nil
+	}
+
+	// The notifier keeps editing this message with the torrent's progress
+	if env.Progress != nil {
+		env.Progress.TrackProgress(torrent.HashString, chatID, messageID)
+	}
+	return nil
+}
+
+// addTorrent adds a torrent into the category directory and labels it with the chat and category.
+func addTorrent(env environment.Env, arg transmission.AddTorrentArg, chatID int64, category Category) (*transmission.Torrent, error) {
+	arg.DownloadDir = fmt.Sprintf("%s/%s", env.DownloadPath, category.String())
+
+	logger.Debug("Adding torrent with category %s to directory %s", category.String(), arg.DownloadDir)
+
+	torrent, err := env.TransmissionClient.AddTorrent(arg)
 
 	if err != nil {
 		logger.Error(err, "Error from transmission RPC")
-		return err
+		return nil, err
 	}
 
 	labels := []string{fmt.Sprintf("%v", chatID), category.String()}
@@ -91,14 +134,9 @@ func (cmd *DownloadCommand) addTorrentAndReply(content []byte, chatID int64, cat
 
 	if err != nil {
 		logger.Error(err, "Error setting torrent labels")
-		return err
+		return nil, err
 	}
 
 	logger.Debug("Torrent %v labels set successfully", torrent.ID)
-
-	cmd.TgApi.SendMessage(telegram.ReplyMessage{
-		ChatId: chatID,
-		Text:   fmt.Sprintf("Added: %v [%s]", torrent.ID, category.DisplayName()),
-	})
-	return nil
+	return torrent, nil
 }
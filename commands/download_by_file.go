@@ -1,13 +1,20 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/minya/logger"
 	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/bencode"
 	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/torrentlink"
 )
 
+// previewTopFiles is how many of the largest files the torrent preview lists.
+const previewTopFiles = 5
+
 type DownloadByFileCommand struct {
 	URL     string
 	Doc     *telegram.Document
@@ -34,8 +41,12 @@ func (factory *DownloadByFileCommandFactory) Accepts(upd *telegram.Update) (bool
 
 func (cmd *DownloadByFileCommand) Handle(upd *telegram.Update) error {
 	api := cmd.TgApi
-	file, err := api.GetFile(cmd.Doc.FileID)
 	chatID := upd.Message.Chat.Id
+	if cmd.Doc.FileSize != nil && *cmd.Doc.FileSize > torrentlink.MaxTorrentSize {
+		cmd.addToBatch(chatID, nil, errors.New("file is too large"))
+		return nil
+	}
+	file, err := api.GetFile(cmd.Doc.FileID)
 	if err != nil {
 		logger.Error(err, "Error getting file")
 		api.SendMessage(telegram.ReplyMessage{
@@ -54,31 +65,49 @@ func (cmd *DownloadByFileCommand) Handle(upd *telegram.Update) error {
 		return err
 	}
 
-	// Store content and show category selection
 	cmd.Content = content
-	keyboard := cmd.buildCategoryKeyboard()
-	cmd.TgApi.SendMessage(telegram.ReplyMessage{
-		ChatId:      chatID,
-		Text:        "Select category:",
-		ReplyMarkup: keyboard,
-	})
+	cmd.addToBatch(chatID, content, nil)
 	return nil
 }
 
-func (cmd *DownloadByFileCommand) buildCategoryKeyboard() telegram.InlineKeyboardMarkup {
-	var buttons [][]telegram.InlineKeyboardButton
+// addToBatch queues the document so that documents sent together get a single category prompt.
+func (cmd *DownloadByFileCommand) addToBatch(chatID int64, content []byte, err error) {
+	var torrent bencode.Torrent
+	if err == nil {
+		torrent, err = bencode.ParseTorrent(content)
+	}
+	if err != nil {
+		logger.Warn("Rejected document %s: %v", cmd.Doc.FileName, err)
+	}
+
+	upload := batchFile{FileID: cmd.Doc.FileID, FileName: cmd.Doc.FileName, Torrent: torrent, Err: err}
+	env := cmd.Env
+	uploadBatcher.Add(chatID, upload, env.UploadBatchWindow, func(files []batchFile) {
+		sendFileBatchPrompt(env, chatID, files)
+	})
+}
 
-	for _, cat := range AllCategories() {
-		button := telegram.InlineKeyboardButton{
-			Text:         cat.DisplayName(),
-			CallbackData: fmt.Sprintf("/dlfilecat %s %s", cat.String(), cmd.Doc.FileID),
-		}
-		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
+// formatTorrentPreview summarises a torrent file before it is added.
+func formatTorrentPreview(torrent bencode.Torrent) string {
+	var sb strings.Builder
+	sb.WriteString(torrent.Name + "\n")
+	if len(torrent.Files) == 1 {
+		sb.WriteString(fmt.Sprintf("Size: %s\n", formatBytes(torrent.TotalSize)))
+	} else {
+		sb.WriteString(fmt.Sprintf("Size: %s in %d files\n", formatBytes(torrent.TotalSize), len(torrent.Files)))
 	}
+	sb.WriteString(fmt.Sprintf("Infohash: %s", torrent.InfoHash))
 
-	return telegram.InlineKeyboardMarkup{
-		InlineKeyboard: buttons,
+	if len(torrent.Files) > 1 {
+		sb.WriteString("\n\nLargest files:")
+		for _, file := range torrent.TopFiles(previewTopFiles) {
+			sb.WriteString(fmt.Sprintf("\n• %s (%s)", file.Path, formatBytes(file.Length)))
+		}
+		if more := len(torrent.Files) - previewTopFiles; more > 0 {
+			sb.WriteString(fmt.Sprintf("\n… and %d more", more))
+		}
 	}
+	return sb.String()
 }
 
 //func (cmd *DownloadByFileCommand) addTorrentAndReply(content []byte, chatID int64, category Category) error {
@@ -9,6 +9,7 @@ import (
 	"github.com/minya/rutracker"
 	"github.com/minya/telegram"
 	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/metrics"
 )
 
 type DownloadWithCategoryCommand struct {
@@ -49,12 +50,16 @@ func (factory *DownloadWithCategoryCommandFactory) Accepts(upd *telegram.Update)
 func (cmd *DownloadWithCategoryCommand) Handle(upd *telegram.Update) error {
 	AnswerCallbackQuery(upd, cmd.TgApi)
 	cfg := cmd.RutrackerConfig
+	start := time.Now()
 	rutrackerClient, err := rutracker.NewAuthenticatedRutrackerClient(cfg.Username, cfg.Password, rutracker.WithTimeout(30*time.Second), rutracker.WithIPv6())
+	metrics.ObserveRutracker(metrics.RutrackerLogin, start, err)
 	if err != nil {
 		logger.Error(err, "Error creating authenticated rutracker client")
 		return err
 	}
+	start = time.Now()
 	torrentBytes, err := rutrackerClient.DownloadTorrent(cmd.URL)
+	metrics.ObserveRutracker(metrics.RutrackerDownload, start, err)
 	if err != nil {
 		return err
 	}
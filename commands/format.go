@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/odwrtw/transmission"
+)
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 GB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatRate renders a transfer rate in bytes per second.
+func formatRate(bytesPerSecond int) string {
+	return formatBytes(int64(bytesPerSecond)) + "/s"
+}
+
+// formatETA renders Transmission's eta field, which is negative when unknown.
+func formatETA(seconds int) string {
+	if seconds < 0 {
+		return "unknown"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// statusName returns a human-readable Transmission torrent status.
+func statusName(status int) string {
+	switch status {
+	case transmission.StatusStopped:
+		return "Paused"
+	case transmission.StatusCheckPending:
+		return "Queued to verify"
+	case transmission.StatusChecking:
+		return "Verifying"
+	case transmission.StatusDownloadPending:
+		return "Queued to download"
+	case transmission.StatusDownloading:
+		return "Downloading"
+	case transmission.StatusSeedPending:
+		return "Queued to seed"
+	case transmission.StatusSeeding:
+		return "Seeding"
+	default:
+		return "Unknown"
+	}
+}
+
+// torrentCategoryLabel returns the display name of the category stored in the torrent's labels.
+func torrentCategoryLabel(torrent *transmission.Torrent) string {
+	if len(torrent.Labels) >= 2 {
+		// labels[0] is chatID, labels[1] is category
+		if cat, ok := ParseCategory(torrent.Labels[1]); ok {
+			return cat.DisplayName()
+		}
+	}
+	return "Unknown"
+}
+
+// progressBarWidth is the number of cells in a progress bar.
+const progressBarWidth = 10
+
+// progressBar renders a fraction between 0 and 1 as a bar of filled and empty cells.
+func progressBar(fraction float64) string {
+	filled := int(fraction * progressBarWidth)
+	filled = max(0, min(filled, progressBarWidth))
+	return strings.Repeat("▓", filled) + strings.Repeat("░", progressBarWidth-filled)
+}
+
+// FormatTorrentProgress renders the live progress message of a torrent: status,
+// progress bar, download speed, ETA and peers.
+func FormatTorrentProgress(torrent *transmission.Torrent) string {
+	return fmt.Sprintf("%s: %s [%s]\n%s %.1f%%\n↓ %s · ETA %s · %d peers",
+		statusName(torrent.Status), torrent.Name, torrentCategoryLabel(torrent),
+		progressBar(torrent.PercentDone), torrent.PercentDone*100,
+		formatRate(torrent.RateDownload), formatETA(torrent.Eta), torrent.PeersConnected)
+}
+
+// FormatTorrentCompleted renders the completion notice of a torrent.
+func FormatTorrentCompleted(torrent *transmission.Torrent) string {
+	return fmt.Sprintf("Completed: %v [%s]", torrent.Name, torrentCategoryLabel(torrent))
+}
+
+// FormatExtraction renders the archive extraction result appended to a
+// completion notice; empty when the torrent had no archives.
+func FormatExtraction(result extract.Result) string {
+	if !result.Found() {
+		return ""
+	}
+	var sb strings.Builder
+	if len(result.Archives) > 0 {
+		fmt.Fprintf(&sb, "📦 Extracted %d archive(s): %d files, %s",
+			len(result.Archives), result.Files, formatBytes(result.Bytes))
+	}
+	for _, failure := range result.Failed {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "⚠ Could not extract %s: %s", failure.Archive, failure.Error)
+	}
+	return sb.String()
+}
+
+// FormatExtractionError renders an extraction that could not start.
+func FormatExtractionError(err error) string {
+	return fmt.Sprintf("⚠ Extraction failed: %v", err)
+}
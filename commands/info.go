@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/odwrtw/transmission"
+)
+
+const (
+	maxInfoFiles    = 10
+	maxInfoTrackers = 5
+)
+
+// InfoCommand shows the details of a single torrent
+type InfoCommand struct {
+	TorrentID int
+	environment.Env
+}
+
+type InfoCommandFactory struct {
+	environment.Env
+}
+
+var reInfoCmd = regexp.MustCompile(`^/info[\s_](\d+)\s*?$`)
+
+func (f *InfoCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil {
+		return false, nil
+	}
+	if found := reInfoCmd.FindStringSubmatch(commandText(upd)); len(found) == 2 {
+		torrentID, err := strconv.Atoi(found[1])
+		if err != nil {
+			return false, nil
+		}
+		return true, &InfoCommand{TorrentID: torrentID, Env: f.Env}
+	}
+	return false, nil
+}
+
+func (cmd *InfoCommand) Handle(upd *telegram.Update) error {
+	chatID := replyChatID(upd, cmd.TgApi)
+
+	text, keyboard, err := prepareTorrentInfo(cmd.Env, chatID, cmd.TorrentID)
+	if err != nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   "Error",
+			ChatId: chatID,
+		})
+		return err
+	}
+
+	msg := telegram.ReplyMessage{
+		Text:   text,
+		ChatId: chatID,
+	}
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	cmd.TgApi.SendMessage(msg)
+	return nil
+}
+
+// editTorrentInfo re-renders an existing /info message, e.g. after an action
+func editTorrentInfo(env environment.Env, chatID int64, messageID int64, torrentID int) error {
+	text, keyboard, err := prepareTorrentInfo(env, chatID, torrentID)
+	if err != nil {
+		return err
+	}
+
+	params := &telegram.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	env.TgApi.EditMessageText(params)
+	return nil
+}
+
+// prepareTorrentInfo fetches one of the chat's torrents and prepares the text and keyboard for display
+func prepareTorrentInfo(env environment.Env, chatID int64, torrentID int) (string, *telegram.InlineKeyboardMarkup, error) {
+	torrent, err := findOwnedTorrent(env.TransmissionClient, torrentID, chatID)
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		return "", nil, err
+	}
+	if torrent == nil {
+		return fmt.Sprintf("Torrent %d not found", torrentID), nil, nil
+	}
+	return formatTorrentInfo(torrent), buildTorrentInfoKeyboard(torrent), nil
+}
+
+func formatTorrentInfo(torrent *transmission.Torrent) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%d. %s\n\n", torrent.ID, torrent.Name)
+	fmt.Fprintf(&sb, "Category: %s\n", torrentCategoryLabel(torrent))
+	fmt.Fprintf(&sb, "Status: %s, %.1f%%\n", statusName(torrent.Status), torrent.PercentDone*100)
+	fmt.Fprintf(&sb, "Size: %s (left: %s)\n", formatBytes(torrent.TotalSize), formatBytes(torrent.LeftUntilDone))
+	fmt.Fprintf(&sb, "Ratio: %.2f (uploaded: %s)\n", torrent.UploadRatio, formatBytes(torrent.UploadedEver))
+	fmt.Fprintf(&sb, "Speed: ↓ %s ↑ %s\n", formatRate(torrent.RateDownload), formatRate(torrent.RateUpload))
+	if torrent.PercentDone < 1 {
+		fmt.Fprintf(&sb, "ETA: %s\n", formatETA(torrent.Eta))
+	}
+	fmt.Fprintf(&sb, "Peers: %d connected, %d sending, %d receiving\n",
+		torrent.PeersConnected, torrent.PeersSendingToUs, torrent.PeersGettingFromUs)
+	fmt.Fprintf(&sb, "Directory: %s\n", torrent.DownloadDir)
+	fmt.Fprintf(&sb, "Labels: %s\n", strings.Join(torrent.Labels, ", "))
+	if torrent.Error != 0 || torrent.ErrorString != "" {
+		fmt.Fprintf(&sb, "Error: %s\n", torrent.ErrorString)
+	}
+
+	if torrent.TrackerStats != nil && len(*torrent.TrackerStats) > 0 {
+		trackers := *torrent.TrackerStats
+		sb.WriteString("\nTrackers:\n")
+		for _, tracker := range trackers[:min(maxInfoTrackers, len(trackers))] {
+			result := tracker.LastAnnounceResult
+			if result == "" {
+				result = "n/a"
+			}
+			fmt.Fprintf(&sb, "• %s: %s, seeders: %d, leechers: %d\n",
+				tracker.Host, result, tracker.SeederCount, tracker.LeecherCount)
+		}
+		if len(trackers) > maxInfoTrackers {
+			fmt.Fprintf(&sb, "… and %d more\n", len(trackers)-maxInfoTrackers)
+		}
+	}
+
+	if torrent.Files != nil && len(*torrent.Files) > 0 {
+		files := *torrent.Files
+		fmt.Fprintf(&sb, "\nFiles (%d):\n", len(files))
+		for _, file := range files[:min(maxInfoFiles, len(files))] {
+			percent := 100.0
+			if file.Length > 0 {
+				percent = float64(file.BytesCompleted) / float64(file.Length) * 100
+			}
+			fmt.Fprintf(&sb, "• %s (%s, %.0f%%)\n", file.Name, formatBytes(file.Length), percent)
+		}
+		if len(files) > maxInfoFiles {
+			fmt.Fprintf(&sb, "… and %d more\n", len(files)-maxInfoFiles)
+		}
+	}
+
+	return sb.String()
+}
+
+func buildTorrentInfoKeyboard(torrent *transmission.Torrent) *telegram.InlineKeyboardMarkup {
+	action := func(text, name string) telegram.InlineKeyboardButton {
+		return telegram.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf("/ta %s %d", name, torrent.ID),
+		}
+	}
+
+	toggle := action("⏸ Pause", actionPause)
+	if torrent.Status == transmission.StatusStopped {
+		toggle = action("▶ Resume", actionResume)
+	}
+
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{toggle, action("🔄 Refresh", actionRefresh)},
+			{action("✔ Verify", actionVerify), action("📣 Reannounce", actionReannounce)},
+			{
+				{
+					Text:         "🗑 Remove",
+					CallbackData: fmt.Sprintf("/remove %d", torrent.ID),
+				},
+			},
+		},
+	}
+}
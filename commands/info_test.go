@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/extract"
+	"github.com/odwrtw/transmission"
+)
+
+func TestInfoCommandFactoryAcceptsMessageAndCallback(t *testing.T) {
+	factory := InfoCommandFactory{}
+	for _, upd := range []telegram.Update{
+		{Message: &telegram.Message{Text: "/info 42"}},
+		{Message: &telegram.Message{Text: "/info_42"}},
+		{CallbackQuery: &telegram.CallbackQuery{Data: "/info 42"}},
+	} {
+		ok, cmd := factory.Accepts(&upd)
+		if !ok {
+			t.Fatalf("expected factory to accept %#v", upd)
+		}
+		infoCmd, ok := cmd.(*InfoCommand)
+		if !ok || infoCmd.TorrentID != 42 {
+			t.Fatalf("expected *InfoCommand for torrent 42, got %#v", cmd)
+		}
+	}
+}
+
+func TestTorrentActionCommandFactory(t *testing.T) {
+	factory := TorrentActionCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/ta reannounce 7"}})
+	if !ok {
+		t.Fatal("expected factory to accept reannounce action")
+	}
+	actionCmd := cmd.(*TorrentActionCommand)
+	if actionCmd.Action != actionReannounce || actionCmd.TorrentID != 7 {
+		t.Fatalf("unexpected command: %#v", actionCmd)
+	}
+
+	if ok, _ := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/ta explode 7"}}); ok {
+		t.Fatal("expected factory to reject unknown action")
+	}
+}
+
+func TestFormatTorrentInfo(t *testing.T) {
+	files := []transmission.File{
+		{Name: "Movie/movie.mkv", Length: 2 << 30, BytesCompleted: 1 << 30},
+	}
+	trackers := []transmission.TrackerStats{
+		{Host: "tracker.example.org", LastAnnounceResult: "Success", SeederCount: 12},
+	}
+	torrent := &transmission.Torrent{
+		ID:           3,
+		Name:         "Movie",
+		Labels:       []string{"111", "movies"},
+		PercentDone:  0.5,
+		TotalSize:    2 << 30,
+		Status:       transmission.StatusDownloading,
+		DownloadDir:  "/downloads/movies",
+		ErrorString:  "Tracker gave HTTP response code 404",
+		Error:        2,
+		Files:        &files,
+		TrackerStats: &trackers,
+	}
+
+	text := formatTorrentInfo(torrent)
+	for _, want := range []string{
+		"3. Movie",
+		"Category: Movies",
+		"Status: Downloading, 50.0%",
+		"Size: 2.0 GB",
+		"Directory: /downloads/movies",
+		"Labels: 111, movies",
+		"Error: Tracker gave HTTP response code 404",
+		"tracker.example.org: Success, seeders: 12",
+		"Files (1):",
+		"movie.mkv (2.0 GB, 50%)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected info to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	keyboard := buildTorrentInfoKeyboard(torrent)
+	if keyboard.InlineKeyboard[0][0].CallbackData != "/ta pause 3" {
+		t.Errorf("expected pause button for a running torrent, got %#v", keyboard.InlineKeyboard[0][0])
+	}
+	torrent.Status = transmission.StatusStopped
+	keyboard = buildTorrentInfoKeyboard(torrent)
+	if keyboard.InlineKeyboard[0][0].CallbackData != "/ta resume 3" {
+		t.Errorf("expected resume button for a paused torrent, got %#v", keyboard.InlineKeyboard[0][0])
+	}
+}
+
+func TestTorrentActionSendsRPC(t *testing.T) {
+	var got struct {
+		Method    string `json:"method"`
+		Arguments struct {
+			Ids []int `json:"ids"`
+		} `json:"arguments"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"arguments":{},"result":"success"}`))
+	}))
+	defer srv.Close()
+
+	client, _ := transmission.New(transmission.Config{Address: srv.URL})
+	if err := torrentAction(client, rpcTorrentVerify, []int{1, 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Method != "torrent-verify" || len(got.Arguments.Ids) != 2 {
+		t.Fatalf("unexpected request: %#v", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		512:           "512 B",
+		1536:          "1.5 KB",
+		5 << 20:       "5.0 MB",
+		3 << 30:       "3.0 GB",
+		(3 << 40) / 2: "1.5 TB",
+	}
+	for n, want := range cases {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFormatTorrentProgress(t *testing.T) {
+	torrent := &transmission.Torrent{
+		Name:           "Movie",
+		Status:         transmission.StatusDownloading,
+		Labels:         []string{"1", "movies"},
+		PercentDone:    0.523,
+		RateDownload:   1536 * 1024,
+		Eta:            300,
+		PeersConnected: 12,
+	}
+
+	want := "Downloading: Movie [Movies]\n▓▓▓▓▓░░░░░ 52.3%\n↓ 1.5 MB/s · ETA 5m0s · 12 peers"
+	if got := FormatTorrentProgress(torrent); got != want {
+		t.Errorf("FormatTorrentProgress() = %q, want %q", got, want)
+	}
+	if got := FormatTorrentCompleted(torrent); got != "Completed: Movie [Movies]" {
+		t.Errorf("FormatTorrentCompleted() = %q", got)
+	}
+}
+
+func TestFormatExtraction(t *testing.T) {
+	if got := FormatExtraction(extract.Result{}); got != "" {
+		t.Errorf("expected no report without archives, got %q", got)
+	}
+
+	got := FormatExtraction(extract.Result{
+		Archives: []string{"movie.part1.rar"},
+		Files:    2,
+		Bytes:    3 * 1024 * 1024,
+		Failed:   []extract.Failure{{Archive: "extras.zip", Error: "zip: not a valid zip file"}},
+	})
+	want := "📦 Extracted 1 archive(s): 2 files, 3.0 MB\n⚠ Could not extract extras.zip: zip: not a valid zip file"
+	if got != want {
+		t.Errorf("FormatExtraction() = %q, want %q", got, want)
+	}
+}
@@ -36,12 +36,13 @@ func (f *ListCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
 
 func (cmd *ListCommand) Handle(upd *telegram.Update) error {
 	chatID := upd.Message.Chat.Id
-	return sendTorrentsList(cmd.Env, chatID, 0)
+	return sendTorrentsList(cmd.Env, listScope{chatID: chatID, isAdmin: isAdmin(cmd.Env, upd)}, 0)
 }
 
 // ListPageCommand handles pagination callbacks for /list
 type ListPageCommand struct {
-	Page int
+	Page     int
+	AllUsers bool
 	environment.Env
 }
 
@@ -49,15 +50,15 @@ type ListPageCommandFactory struct {
 	environment.Env
 }
 
-var reListPageCmd = regexp.MustCompile(`^/list_page\s+(\d+)$`)
+var reListPageCmd = regexp.MustCompile(`^/list_page\s+(\d+)(\s+all)?$`)
 
 func (f *ListPageCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
 	if upd == nil || upd.CallbackQuery == nil {
 		return false, nil
 	}
-	if found := reListPageCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 2 {
+	if found := reListPageCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 3 {
 		page, _ := strconv.Atoi(found[1])
-		return true, &ListPageCommand{Page: page, Env: f.Env}
+		return true, &ListPageCommand{Page: page, AllUsers: found[2] != "", Env: f.Env}
 	}
 	return false, nil
 }
@@ -66,23 +67,33 @@ func (cmd *ListPageCommand) Handle(upd *telegram.Update) error {
 	AnswerCallbackQuery(upd, cmd.TgApi)
 	chatID := upd.CallbackQuery.Message.Chat.Id
 	messageID := upd.CallbackQuery.Message.MessageId
-	return editTorrentsList(cmd.Env, chatID, messageID, cmd.Page)
+	scope := listScope{chatID: chatID, isAdmin: isAdmin(cmd.Env, upd)}
+	// Only admins can see everyone's torrents
+	scope.allUsers = cmd.AllUsers && scope.isAdmin
+	return editTorrentsList(cmd.Env, scope, messageID, cmd.Page)
+}
+
+// listScope describes whose torrents a /list message shows
+type listScope struct {
+	chatID   int64
+	isAdmin  bool
+	allUsers bool
 }
 
 // sendTorrentsList sends a new message with the torrent list (for initial /list command)
-func sendTorrentsList(env environment.Env, chatID int64, page int) error {
-	text, keyboard, err := prepareTorrentsList(env, page)
+func sendTorrentsList(env environment.Env, scope listScope, page int) error {
+	text, keyboard, err := prepareTorrentsList(env, scope, page)
 	if err != nil {
 		env.TgApi.SendMessage(telegram.ReplyMessage{
 			Text:   "Error",
-			ChatId: chatID,
+			ChatId: scope.chatID,
 		})
 		return err
 	}
 
 	msg := telegram.ReplyMessage{
 		Text:   text,
-		ChatId: chatID,
+		ChatId: scope.chatID,
 	}
 	if keyboard != nil {
 		msg.ReplyMarkup = keyboard
@@ -93,14 +104,14 @@ func sendTorrentsList(env environment.Env, chatID int64, page int) error {
 }
 
 // editTorrentsList edits an existing message with the torrent list (for pagination)
-func editTorrentsList(env environment.Env, chatID int64, messageID int64, page int) error {
-	text, keyboard, err := prepareTorrentsList(env, page)
+func editTorrentsList(env environment.Env, scope listScope, messageID int64, page int) error {
+	text, keyboard, err := prepareTorrentsList(env, scope, page)
 	if err != nil {
 		return err
 	}
 
 	params := &telegram.EditMessageTextParams{
-		ChatID:    chatID,
+		ChatID:    scope.chatID,
 		MessageID: messageID,
 		Text:      text,
 	}
@@ -113,7 +124,7 @@ func editTorrentsList(env environment.Env, chatID int64, messageID int64, page i
 }
 
 // prepareTorrentsList fetches torrents and prepares the text and keyboard for display
-func prepareTorrentsList(env environment.Env, page int) (string, *telegram.InlineKeyboardMarkup, error) {
+func prepareTorrentsList(env environment.Env, scope listScope, page int) (string, *telegram.InlineKeyboardMarkup, error) {
 	torrents, err := env.TransmissionClient.GetTorrents()
 	if err != nil {
 		logger.Error(err, "Error getting torrents")
@@ -121,8 +132,12 @@ func prepareTorrentsList(env environment.Env, page int) (string, *telegram.Inlin
 	}
 	logger.Debug("Torrents received, count: %d", len(torrents))
 
+	if !scope.allUsers {
+		torrents = ownedTorrents(torrents, scope.chatID)
+	}
+
 	if len(torrents) == 0 {
-		keyboard := buildPaginationKeyboard(0, 1, env.WebAppURL)
+		keyboard := buildPaginationKeyboard(nil, scope, 0, 1, env.WebAppURL)
 		return "No active torrents", keyboard, nil
 	}
 
@@ -147,13 +162,13 @@ func prepareTorrentsList(env environment.Env, page int) (string, *telegram.Inlin
 
 	pageTorrents := torrents[start:end]
 
-	text := formatTorrentsList(pageTorrents, page, totalPages, len(torrents))
-	keyboard := buildPaginationKeyboard(page, totalPages, env.WebAppURL)
+	text := formatTorrentsList(pageTorrents, scope, page, totalPages, len(torrents))
+	keyboard := buildPaginationKeyboard(pageTorrents, scope, page, totalPages, env.WebAppURL)
 
 	return text, keyboard, nil
 }
 
-func formatTorrentsList(torrents []*transmission.Torrent, page, totalPages, total int) string {
+func formatTorrentsList(torrents []*transmission.Torrent, scope listScope, page, totalPages, total int) string {
 	var sb strings.Builder
 
 	for _, torrent := range torrents {
@@ -167,34 +182,56 @@ func formatTorrentsList(torrents []*transmission.Torrent, page, totalPages, tota
 				logger.Debug("Failed to parse category from label: %s", torrent.Labels[1])
 			}
 		}
-		fmt.Fprintf(&sb, "%v [%s] %v %.0f%%\n\n", torrent.ID, categoryLabel, torrent.Name, torrent.PercentDone*100)
+		fmt.Fprintf(&sb, "%v [%s] %v %.0f%%", torrent.ID, categoryLabel, torrent.Name, torrent.PercentDone*100)
+		if scope.allUsers {
+			fmt.Fprintf(&sb, " (owner: %s)", torrentOwnerLabel(torrent, scope.chatID))
+		}
+		sb.WriteString("\n\n")
 	}
 
+	if scope.allUsers {
+		sb.WriteString("All users. ")
+	}
 	fmt.Fprintf(&sb, "Page %d/%d (total: %d)", page+1, totalPages, total)
 	return sb.String()
 }
 
-func buildPaginationKeyboard(page, totalPages int, webAppURL string) *telegram.InlineKeyboardMarkup {
+func buildPaginationKeyboard(pageTorrents []*transmission.Torrent, scope listScope, page, totalPages int, webAppURL string) *telegram.InlineKeyboardMarkup {
 	var rows [][]telegram.InlineKeyboardButton
 
-	// Pagination buttons row
-	var navButtons []telegram.InlineKeyboardButton
-	if page > 0 {
-		navButtons = append(navButtons, telegram.InlineKeyboardButton{
-			Text:         "← Back",
-			CallbackData: fmt.Sprintf("/list_page %d", page-1),
+	// Info buttons row, one per torrent of the caller on the page
+	var infoButtons []telegram.InlineKeyboardButton
+	for _, torrent := range pageTorrents {
+		if !isTorrentOwner(torrent, scope.chatID) {
+			continue
+		}
+		infoButtons = append(infoButtons, telegram.InlineKeyboardButton{
+			Text:         fmt.Sprintf("ℹ %d", torrent.ID),
+			CallbackData: fmt.Sprintf("/info %d", torrent.ID),
 		})
 	}
-	if page < totalPages-1 {
-		navButtons = append(navButtons, telegram.InlineKeyboardButton{
-			Text:         "Next →",
-			CallbackData: fmt.Sprintf("/list_page %d", page+1),
-		})
+	if len(infoButtons) > 0 {
+		rows = append(rows, infoButtons)
+	}
+
+	// Pagination buttons row
+	pageCallback := "/list_page %d"
+	if scope.allUsers {
+		pageCallback = "/list_page %d all"
 	}
-	if len(navButtons) > 0 {
+	if navButtons := buildNavButtons(page, totalPages, pageCallback); len(navButtons) > 0 {
 		rows = append(rows, navButtons)
 	}
 
+	// All users toggle row (admins only)
+	if scope.isAdmin {
+		toggle := telegram.InlineKeyboardButton{Text: "👥 All users", CallbackData: "/list_page 0 all"}
+		if scope.allUsers {
+			toggle = telegram.InlineKeyboardButton{Text: "👤 Only mine", CallbackData: "/list_page 0"}
+		}
+		rows = append(rows, []telegram.InlineKeyboardButton{toggle})
+	}
+
 	// Web app button row (only if URL is configured)
 	if webAppURL != "" {
 		rows = append(rows, []telegram.InlineKeyboardButton{
@@ -213,3 +250,34 @@ func buildPaginationKeyboard(page, totalPages int, webAppURL string) *telegram.I
 		InlineKeyboard: rows,
 	}
 }
+
+// torrentOwnerLabel returns the owner of a torrent for the all users view
+func torrentOwnerLabel(torrent *transmission.Torrent, chatID int64) string {
+	switch {
+	case isTorrentOwner(torrent, chatID):
+		return "you"
+	case len(torrent.Labels) > 0 && torrent.Labels[0] != "":
+		return torrent.Labels[0]
+	default:
+		return "unknown"
+	}
+}
+
+// buildNavButtons returns Back/Next buttons for a paginated message. callbackFormat
+// must contain a single %d verb that receives the target page.
+func buildNavButtons(page, totalPages int, callbackFormat string) []telegram.InlineKeyboardButton {
+	var navButtons []telegram.InlineKeyboardButton
+	if page > 0 {
+		navButtons = append(navButtons, telegram.InlineKeyboardButton{
+			Text:         "← Back",
+			CallbackData: fmt.Sprintf(callbackFormat, page-1),
+		})
+	}
+	if page < totalPages-1 {
+		navButtons = append(navButtons, telegram.InlineKeyboardButton{
+			Text:         "Next →",
+			CallbackData: fmt.Sprintf(callbackFormat, page+1),
+		})
+	}
+	return navButtons
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/odwrtw/transmission"
+)
+
+func TestListPageCommandFactoryAllUsers(t *testing.T) {
+	factory := ListPageCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/list_page 2 all"}})
+	if !ok {
+		t.Fatal("expected factory to accept all users page callback")
+	}
+	if pageCmd := cmd.(*ListPageCommand); pageCmd.Page != 2 || !pageCmd.AllUsers {
+		t.Errorf("unexpected command %#v", pageCmd)
+	}
+
+	ok, cmd = factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/list_page 1"}})
+	if !ok || cmd.(*ListPageCommand).AllUsers {
+		t.Errorf("expected own torrents page, got %v %#v", ok, cmd)
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	env := environment.Env{AdminUsers: []int64{42}}
+	admin := &telegram.Update{Message: &telegram.Message{From: &telegram.User{Id: 42}}}
+	user := &telegram.Update{CallbackQuery: &telegram.CallbackQuery{From: &telegram.User{Id: 7}}}
+
+	if !isAdmin(env, admin) {
+		t.Error("expected user 42 to be admin")
+	}
+	if isAdmin(env, user) {
+		t.Error("expected user 7 not to be admin")
+	}
+}
+
+func TestFormatTorrentsListAllUsersShowsOwners(t *testing.T) {
+	torrents := []*transmission.Torrent{
+		{ID: 2, Name: "Mine", Labels: []string{"111", "movies"}},
+		{ID: 1, Name: "Theirs", Labels: []string{"222", "shows"}},
+	}
+	scope := listScope{chatID: 111, isAdmin: true, allUsers: true}
+
+	text := formatTorrentsList(torrents, scope, 0, 1, 2)
+	if !strings.Contains(text, "Mine 0% (owner: you)") || !strings.Contains(text, "Theirs 0% (owner: 222)") {
+		t.Errorf("expected owners in all users view, got %q", text)
+	}
+
+	keyboard := buildPaginationKeyboard(torrents, scope, 0, 1, "")
+	infoRow := keyboard.InlineKeyboard[0]
+	if len(infoRow) != 1 || infoRow[0].CallbackData != "/info 2" {
+		t.Errorf("expected info button for own torrent only, got %#v", infoRow)
+	}
+	toggle := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1][0]
+	if toggle.CallbackData != "/list_page 0" {
+		t.Errorf("expected toggle back to own torrents, got %#v", toggle)
+	}
+}
+
+func TestBuildPaginationKeyboardNoToggleForUsers(t *testing.T) {
+	scope := listScope{chatID: 111}
+	if keyboard := buildPaginationKeyboard(nil, scope, 0, 1, ""); keyboard != nil {
+		t.Errorf("expected no keyboard for a regular user with a single page, got %#v", keyboard)
+	}
+}
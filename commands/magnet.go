@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/magnet"
+	"github.com/odwrtw/transmission"
+)
+
+const (
+	pendingMagnetTTL  = time.Hour
+	maxPendingMagnets = 256
+)
+
+// pendingMagnets keeps magnet URIs between the category prompt and the category
+// choice; they are far too long for callback data.
+var pendingMagnets = newTokenStore[string](pendingMagnetTTL, maxPendingMagnets)
+
+// MagnetCommand asks for a category for a pasted magnet link
+type MagnetCommand struct {
+	Text string
+	environment.Env
+}
+
+type MagnetCommandFactory struct {
+	environment.Env
+}
+
+func (factory *MagnetCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.Message == nil {
+		return false, nil
+	}
+	if magnet.IsMagnet(upd.Message.Text) {
+		return true, &MagnetCommand{
+			Text: upd.Message.Text,
+			Env:  factory.Env,
+		}
+	}
+	return false, nil
+}
+
+func (cmd *MagnetCommand) Handle(upd *telegram.Update) error {
+	chatID := upd.Message.Chat.Id
+
+	link, err := magnet.Parse(cmd.Text)
+	if err != nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   fmt.Sprintf("Invalid magnet link: %v", err),
+		})
+		return nil
+	}
+
+	token, err := pendingMagnets.Put(link.URI)
+	if err != nil {
+		logger.Error(err, "Error storing magnet link")
+		return err
+	}
+
+	keyboard := buildCategoryKeyboard("/dlmag", token)
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId:      chatID,
+		Text:        fmt.Sprintf("Magnet: %s\n\nSelect category:", link.Name()),
+		ReplyMarkup: keyboard,
+	})
+	return nil
+}
+
+// DownloadMagnetWithCategoryCommand adds a pending magnet link with the chosen category
+type DownloadMagnetWithCategoryCommand struct {
+	Token    string
+	Category Category
+	environment.Env
+}
+
+type DownloadMagnetWithCategoryCommandFactory struct {
+	environment.Env
+}
+
+var reDownloadMagnetWithCategoryCmd = regexp.MustCompile(`^/dlmag\s+(\S+)\s+([0-9a-f]+)$`)
+
+func (factory *DownloadMagnetWithCategoryCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	if found := reDownloadMagnetWithCategoryCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 3 {
+		categoryStr := strings.TrimSpace(found[1])
+
+		category, ok := ParseCategory(categoryStr)
+		if !ok {
+			logger.Error(nil, "Invalid category: %s", categoryStr)
+			return false, nil
+		}
+
+		return true, &DownloadMagnetWithCategoryCommand{
+			Token:    found[2],
+			Category: category,
+			Env:      factory.Env,
+		}
+	}
+	return false, nil
+}
+
+func (cmd *DownloadMagnetWithCategoryCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+
+	uri, ok := pendingMagnets.Get(cmd.Token)
+	if !ok {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "Magnet link expired, please send it again",
+		})
+		return nil
+	}
+
+	return addTorrentArgAndReply(cmd.Env, transmission.AddTorrentArg{Filename: uri}, chatID, cmd.Category)
+}
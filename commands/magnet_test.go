@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/minya/telegram"
+)
+
+func TestMagnetCommandFactoryAcceptsMagnet(t *testing.T) {
+	factory := MagnetCommandFactory{}
+	upd := telegram.Update{
+		Message: &telegram.Message{
+			Text: "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Movie",
+		},
+	}
+
+	ok, cmd := factory.Accepts(&upd)
+	if !ok {
+		t.Fatal("expected factory to accept magnet link")
+	}
+	if _, ok := cmd.(*MagnetCommand); !ok {
+		t.Fatalf("expected *MagnetCommand, got %T", cmd)
+	}
+
+	searchFactory := SearchCommandFactory{}
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "movie title"}}); ok {
+		t.Error("expected magnet factory to reject plain text")
+	}
+	if ok, _ := searchFactory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "movie title"}}); !ok {
+		t.Error("expected plain text to still be a search")
+	}
+}
+
+func TestDownloadMagnetWithCategoryCommandFactory(t *testing.T) {
+	factory := DownloadMagnetWithCategoryCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/dlmag shows 0a1b2c3d"}})
+	if !ok {
+		t.Fatal("expected factory to accept magnet category callback")
+	}
+	magnetCmd := cmd.(*DownloadMagnetWithCategoryCommand)
+	if magnetCmd.Token != "0a1b2c3d" || magnetCmd.Category != CategoryShows {
+		t.Errorf("unexpected command %#v", magnetCmd)
+	}
+
+	if ok, _ := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/dlmag nope 0a1b2c3d"}}); ok {
+		t.Error("expected factory to reject invalid category")
+	}
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 
 	"github.com/minya/logger"
@@ -10,6 +12,9 @@ import (
 	"github.com/odwrtw/transmission"
 )
 
+const maxPickerNameLength = 40
+
+// RemoveTorrentCommand asks the caller to confirm removing one of their torrents
 type RemoveTorrentCommand struct {
 	TorrentID int
 	environment.Env
@@ -22,10 +27,10 @@ type RemoveTorrentCommandFactory struct {
 var reRemCmd = regexp.MustCompile(`^/remove\s(\d+)\s*?$`)
 
 func (factory *RemoveTorrentCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
-	if upd == nil || upd.Message == nil {
+	if upd == nil {
 		return false, nil
 	}
-	if found := reRemCmd.FindStringSubmatch(upd.Message.Text); len(found) == 2 {
+	if found := reRemCmd.FindStringSubmatch(commandText(upd)); len(found) == 2 {
 		torrentID, err := strconv.Atoi(found[1])
 		if err != nil {
 			return false, nil
@@ -40,27 +45,229 @@ func (factory *RemoveTorrentCommandFactory) Accepts(upd *telegram.Update) (bool,
 }
 
 func (cmd *RemoveTorrentCommand) Handle(upd *telegram.Update) error {
-	allTorrents, err := cmd.TransmissionClient.GetTorrents()
+	chatID := replyChatID(upd, cmd.TgApi)
+
+	torrent, err := findOwnedTorrent(cmd.TransmissionClient, cmd.TorrentID, chatID)
 	if err != nil {
 		logger.Error(err, "Error getting torrents")
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   "Error",
+			ChatId: chatID,
+		})
 		return err
 	}
+	if torrent == nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   fmt.Sprintf("Torrent %d not found", cmd.TorrentID),
+			ChatId: chatID,
+		})
+		return nil
+	}
 
-	torrents := []*transmission.Torrent{}
-	for _, torrent := range allTorrents {
-		if torrent.ID == cmd.TorrentID {
-			torrents = append(torrents, torrent)
-		}
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		Text:        fmt.Sprintf("Remove %d %s?", torrent.ID, torrent.Name),
+		ChatId:      chatID,
+		ReplyMarkup: buildRemoveConfirmKeyboard(torrent.ID),
+	})
+	return nil
+}
+
+func buildRemoveConfirmKeyboard(torrentID int) *telegram.InlineKeyboardMarkup {
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{{Text: "Remove torrent only", CallbackData: fmt.Sprintf("/rmconfirm %d keep", torrentID)}},
+			{{Text: "Remove torrent and data", CallbackData: fmt.Sprintf("/rmconfirm %d data", torrentID)}},
+			{{Text: "Cancel", CallbackData: fmt.Sprintf("/rmconfirm %d cancel", torrentID)}},
+		},
+	}
+}
+
+// RemoveConfirmCommand removes a torrent once the caller picked what to do with its data
+type RemoveConfirmCommand struct {
+	TorrentID  int
+	DeleteData bool
+	Cancel     bool
+	environment.Env
+}
+
+type RemoveConfirmCommandFactory struct {
+	environment.Env
+}
+
+var reRemConfirmCmd = regexp.MustCompile(`^/rmconfirm\s+(\d+)\s+(keep|data|cancel)$`)
+
+func (factory *RemoveConfirmCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	found := reRemConfirmCmd.FindStringSubmatch(upd.CallbackQuery.Data)
+	if len(found) != 3 {
+		return false, nil
 	}
-	err = cmd.TransmissionClient.RemoveTorrents(torrents, true)
+	torrentID, err := strconv.Atoi(found[1])
+	if err != nil {
+		return false, nil
+	}
+	return true, &RemoveConfirmCommand{
+		TorrentID:  torrentID,
+		DeleteData: found[2] == "data",
+		Cancel:     found[2] == "cancel",
+		Env:        factory.Env,
+	}
+}
+
+func (cmd *RemoveConfirmCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+	messageID := upd.CallbackQuery.Message.MessageId
+	reply := func(text string) {
+		cmd.TgApi.EditMessageText(&telegram.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      text,
+		})
+	}
+
+	if cmd.Cancel {
+		reply("Cancelled.")
+		return nil
+	}
+
+	torrent, err := findOwnedTorrent(cmd.TransmissionClient, cmd.TorrentID, chatID)
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		reply("Error")
+		return err
+	}
+	if torrent == nil {
+		reply(fmt.Sprintf("Torrent %d not found, nothing removed.", cmd.TorrentID))
+		return nil
+	}
+
+	err = cmd.TransmissionClient.RemoveTorrents([]*transmission.Torrent{torrent}, cmd.DeleteData)
 	if err != nil {
 		logger.Error(err, "Error removing torrents")
+		reply(fmt.Sprintf("Failed to remove %d %s.", torrent.ID, torrent.Name))
 		return err
 	}
-	chatID := upd.Message.Chat.Id
-	cmd.TgApi.SendMessage(telegram.ReplyMessage{
-		Text:   "Removed.",
+
+	logger.Info("Removed torrent %d: %s for chat %d (delete data: %v)", torrent.ID, torrent.Name, chatID, cmd.DeleteData)
+	reply(formatRemoveResult(torrent, cmd.DeleteData))
+	return nil
+}
+
+func formatRemoveResult(torrent *transmission.Torrent, deleteData bool) string {
+	if deleteData {
+		return fmt.Sprintf("Removed %d %s and deleted its data.", torrent.ID, torrent.Name)
+	}
+	return fmt.Sprintf("Removed %d %s, data kept in %s.", torrent.ID, torrent.Name, torrent.DownloadDir)
+}
+
+// RemovePickerCommand shows the caller's torrents to pick one for removal (/remove without arguments)
+type RemovePickerCommand struct {
+	Page int
+	environment.Env
+}
+
+type RemovePickerCommandFactory struct {
+	environment.Env
+}
+
+var (
+	reRemPickerCmd     = regexp.MustCompile(`^/remove\s*?$`)
+	reRemPickerPageCmd = regexp.MustCompile(`^/remove_page\s+(\d+)$`)
+)
+
+func (factory *RemovePickerCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil {
+		return false, nil
+	}
+	if upd.Message != nil && reRemPickerCmd.MatchString(upd.Message.Text) {
+		return true, &RemovePickerCommand{Env: factory.Env}
+	}
+	if upd.CallbackQuery != nil {
+		if found := reRemPickerPageCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 2 {
+			page, _ := strconv.Atoi(found[1])
+			return true, &RemovePickerCommand{Page: page, Env: factory.Env}
+		}
+	}
+	return false, nil
+}
+
+func (cmd *RemovePickerCommand) Handle(upd *telegram.Update) error {
+	chatID := replyChatID(upd, cmd.TgApi)
+
+	allTorrents, err := cmd.TransmissionClient.GetTorrents()
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   "Error",
+			ChatId: chatID,
+		})
+		return err
+	}
+
+	text, keyboard := prepareRemovePicker(ownedTorrents(allTorrents, chatID), cmd.Page)
+
+	if upd.CallbackQuery != nil {
+		params := &telegram.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: upd.CallbackQuery.Message.MessageId,
+			Text:      text,
+		}
+		if keyboard != nil {
+			params.ReplyMarkup = keyboard
+		}
+		cmd.TgApi.EditMessageText(params)
+		return nil
+	}
+
+	msg := telegram.ReplyMessage{
+		Text:   text,
 		ChatId: chatID,
-	})
+	}
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	cmd.TgApi.SendMessage(msg)
 	return nil
 }
+
+// prepareRemovePicker renders one page of the caller's torrents as removal buttons
+func prepareRemovePicker(torrents []*transmission.Torrent, page int) (string, *telegram.InlineKeyboardMarkup) {
+	if len(torrents) == 0 {
+		return "You have no torrents to remove", nil
+	}
+
+	sort.Slice(torrents, func(i, j int) bool {
+		return torrents[i].ID > torrents[j].ID
+	})
+
+	totalPages := (len(torrents) + pageSize - 1) / pageSize
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	start := page * pageSize
+	end := min(start+pageSize, len(torrents))
+
+	var rows [][]telegram.InlineKeyboardButton
+	for _, torrent := range torrents[start:end] {
+		name := []rune(torrent.Name)
+		if len(name) > maxPickerNameLength {
+			name = append(name[:maxPickerNameLength-1], '…')
+		}
+		rows = append(rows, []telegram.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("%d %s", torrent.ID, string(name)),
+			CallbackData: fmt.Sprintf("/remove %d", torrent.ID),
+		}})
+	}
+	if navButtons := buildNavButtons(page, totalPages, "/remove_page %d"); len(navButtons) > 0 {
+		rows = append(rows, navButtons)
+	}
+
+	text := fmt.Sprintf("Select a torrent to remove (page %d/%d):", page+1, totalPages)
+	return text, &telegram.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/odwrtw/transmission"
+)
+
+func TestRemoveTorrentCommandFactoryAcceptsMessageAndCallback(t *testing.T) {
+	factory := RemoveTorrentCommandFactory{}
+	for _, upd := range []telegram.Update{
+		{Message: &telegram.Message{Text: "/remove 9"}},
+		{CallbackQuery: &telegram.CallbackQuery{Data: "/remove 9"}},
+	} {
+		ok, cmd := factory.Accepts(&upd)
+		if !ok {
+			t.Fatalf("expected factory to accept %#v", upd)
+		}
+		if removeCmd := cmd.(*RemoveTorrentCommand); removeCmd.TorrentID != 9 {
+			t.Fatalf("expected torrent 9, got %d", removeCmd.TorrentID)
+		}
+	}
+}
+
+func TestRemoveConfirmCommandFactory(t *testing.T) {
+	factory := RemoveConfirmCommandFactory{}
+	cases := []struct {
+		data       string
+		deleteData bool
+		cancel     bool
+	}{
+		{"/rmconfirm 5 keep", false, false},
+		{"/rmconfirm 5 data", true, false},
+		{"/rmconfirm 5 cancel", false, true},
+	}
+	for _, c := range cases {
+		ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: c.data}})
+		if !ok {
+			t.Fatalf("expected factory to accept %q", c.data)
+		}
+		confirmCmd := cmd.(*RemoveConfirmCommand)
+		if confirmCmd.TorrentID != 5 || confirmCmd.DeleteData != c.deleteData || confirmCmd.Cancel != c.cancel {
+			t.Errorf("%q: unexpected command %#v", c.data, confirmCmd)
+		}
+	}
+
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/rmconfirm 5 data"}}); ok {
+		t.Error("expected factory to reject typed confirmation")
+	}
+}
+
+func TestRemovePickerCommandFactory(t *testing.T) {
+	factory := RemovePickerCommandFactory{}
+
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/remove"}}); !ok {
+		t.Error("expected factory to accept /remove without arguments")
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/remove 3"}}); ok {
+		t.Error("expected factory to reject /remove with an ID")
+	}
+	ok, cmd := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/remove_page 2"}})
+	if !ok || cmd.(*RemovePickerCommand).Page != 2 {
+		t.Errorf("expected picker page 2, got %v %#v", ok, cmd)
+	}
+}
+
+func TestPrepareRemovePicker(t *testing.T) {
+	var torrents []*transmission.Torrent
+	for i := 1; i <= 7; i++ {
+		torrents = append(torrents, &transmission.Torrent{ID: i, Name: strings.Repeat("x", 60)})
+	}
+
+	text, keyboard := prepareRemovePicker(torrents, 0)
+	if !strings.Contains(text, "page 1/2") {
+		t.Errorf("unexpected text %q", text)
+	}
+	if len(keyboard.InlineKeyboard) != pageSize+1 {
+		t.Fatalf("expected %d torrent rows and a navigation row, got %d rows", pageSize, len(keyboard.InlineKeyboard))
+	}
+	first := keyboard.InlineKeyboard[0][0]
+	if first.CallbackData != "/remove 7" {
+		t.Errorf("expected newest torrent first, got %q", first.CallbackData)
+	}
+	if len([]rune(first.Text)) > maxPickerNameLength+3 {
+		t.Errorf("expected long names to be truncated, got %q", first.Text)
+	}
+
+	if text, keyboard := prepareRemovePicker(nil, 0); keyboard != nil || text == "" {
+		t.Errorf("expected an empty picker message without keyboard, got %q %#v", text, keyboard)
+	}
+}
+
+func TestFormatRemoveResult(t *testing.T) {
+	torrent := &transmission.Torrent{ID: 4, Name: "Show", DownloadDir: "/downloads/shows"}
+	if got := formatRemoveResult(torrent, false); got != "Removed 4 Show, data kept in /downloads/shows." {
+		t.Errorf("unexpected keep-data result %q", got)
+	}
+	if got := formatRemoveResult(torrent, true); got != "Removed 4 Show and deleted its data." {
+		t.Errorf("unexpected delete-data result %q", got)
+	}
+}
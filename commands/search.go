@@ -3,7 +3,7 @@ package commands
 import (
 	"fmt"
 	"regexp"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,12 +11,18 @@ import (
 	"github.com/minya/rutracker"
 	"github.com/minya/telegram"
 	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/metrics"
+	"github.com/minya/tgtorrentbot/searchquery"
 )
 
 var reSearchCmd = regexp.MustCompile(`^/search\s(.+?)$`)
 
+const searchPageSize = 5
+
 type SearchCommand struct {
-	Pattern string
+	Pattern  string
+	Query    searchquery.Query
+	queryErr error
 	environment.Env
 }
 
@@ -40,32 +46,41 @@ func Match(cmdText string) *SearchCommand {
 		return nil
 	}
 	if found := reSearchCmd.FindStringSubmatch(cmdText); len(found) == 2 {
-		pattern := strings.TrimSpace(found[1])
-		return &SearchCommand{Pattern: pattern}
+		return newSearchCommand(strings.TrimSpace(found[1]))
 	}
 	if cmdText[0] != '/' {
-		pattern := strings.TrimSpace(cmdText)
-		return &SearchCommand{Pattern: pattern}
+		return newSearchCommand(strings.TrimSpace(cmdText))
 	}
 	return nil
 }
 
-func min(a int, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+func newSearchCommand(pattern string) *SearchCommand {
+	query, err := searchquery.Parse(pattern)
+	return &SearchCommand{Pattern: pattern, Query: query, queryErr: err}
 }
 
 func (cmd *SearchCommand) Handle(upd *telegram.Update) error {
+	chatID := upd.Message.Chat.Id
+	if cmd.queryErr != nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   cmd.queryErr.Error(),
+		})
+		return nil
+	}
+
 	logger.Info("Starting search, pattern: %s", cmd.Pattern)
 	cfg := cmd.RutrackerConfig
+	start := time.Now()
 	rutrackerClient, err := rutracker.NewAuthenticatedRutrackerClient(cfg.Username, cfg.Password, rutracker.WithTimeout(30*time.Second), rutracker.WithIPv6())
+	metrics.ObserveRutracker(metrics.RutrackerLogin, start, err)
 	if err != nil {
 		logger.Error(err, "Error creating authenticated rutracker client")
 		return err
 	}
-	found, err := rutrackerClient.Find(cmd.Pattern)
+	start = time.Now()
+	found, err := rutrackerClient.Find(cmd.Query.Text)
+	metrics.ObserveRutracker(metrics.RutrackerSearch, start, err)
 	if err != nil {
 		logger.Error(err, "Error searching")
 		return err
@@ -74,7 +89,7 @@ func (cmd *SearchCommand) Handle(upd *telegram.Update) error {
 	logger.Info("found: %v results\n", len(found))
 	logger.Debug("found: %v\n", found)
 
-	chatID := upd.Message.Chat.Id
+	found = cmd.Query.Apply(found)
 	if len(found) == 0 {
 		cmd.TgApi.SendMessage(telegram.ReplyMessage{
 			ChatId: chatID,
@@ -83,25 +98,150 @@ func (cmd *SearchCommand) Handle(upd *telegram.Update) error {
 		return nil
 	}
 
-	sort.Slice(found, func(i, j int) bool {
-		return found[i].Seeders > found[j].Seeders
+	session := &searchSession{Query: cmd.Pattern, Items: found}
+	token, err := searchSessions.Put(session)
+	if err != nil {
+		logger.Error(err, "Error creating search session")
+		return err
+	}
+
+	text, keyboard := prepareSearchResultsPage(session, token, 0)
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId:      chatID,
+		Text:        text,
+		ReplyMarkup: keyboard,
 	})
+	return nil
+}
 
-	for _, f := range found[:min(10, len(found))] {
-		cmd.TgApi.SendMessage(telegram.ReplyMessage{
-			ChatId: chatID,
-			Text:   fmt.Sprintf("%v\r\n\r\nSize:%v%v\r\nSeeders: %v", f.Title, f.Size.Size, f.Size.Unit, f.Seeders),
-			ReplyMarkup: &telegram.InlineKeyboardMarkup{
-				InlineKeyboard: [][]telegram.InlineKeyboardButton{
-					{
-						{
-							Text:         "Download",
-							CallbackData: fmt.Sprintf("/dl %v", f.DownloadURL),
-						},
-					},
-				},
-			},
+// SearchPageCommand handles pagination callbacks for search results
+type SearchPageCommand struct {
+	Token string
+	Page  int
+	environment.Env
+}
+
+type SearchPageCommandFactory struct {
+	environment.Env
+}
+
+var reSearchPageCmd = regexp.MustCompile(`^/search_page\s+([0-9a-f]+)\s+(\d+)$`)
+
+func (f *SearchPageCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	if found := reSearchPageCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 3 {
+		page, _ := strconv.Atoi(found[2])
+		return true, &SearchPageCommand{Token: found[1], Page: page, Env: f.Env}
+	}
+	return false, nil
+}
+
+func (cmd *SearchPageCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+	messageID := upd.CallbackQuery.Message.MessageId
+
+	session, ok := searchSessions.Get(cmd.Token)
+	if !ok {
+		cmd.TgApi.EditMessageText(&telegram.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      searchExpiredText,
 		})
+		return nil
+	}
+
+	text, keyboard := prepareSearchResultsPage(session, cmd.Token, cmd.Page)
+	params := &telegram.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
 	}
+	cmd.TgApi.EditMessageText(params)
+	return nil
+}
+
+// SearchDownloadCommandFactory resolves a search result picked from a results
+// page and hands it over to DownloadCommand for category selection.
+type SearchDownloadCommandFactory struct {
+	environment.Env
+}
+
+var reSearchDownloadCmd = regexp.MustCompile(`^/sdl\s+([0-9a-f]+)\s+(\d+)$`)
+
+func (f *SearchDownloadCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	found := reSearchDownloadCmd.FindStringSubmatch(upd.CallbackQuery.Data)
+	if len(found) != 3 {
+		return false, nil
+	}
+	session, ok := searchSessions.Get(found[1])
+	index, _ := strconv.Atoi(found[2])
+	if !ok || index >= len(session.Items) {
+		return true, &SearchExpiredCommand{Env: f.Env}
+	}
+	return true, &DownloadCommand{
+		URL: session.Items[index].DownloadURL,
+		Env: f.Env,
+	}
+}
+
+const searchExpiredText = "Search results expired, please search again"
+
+// SearchExpiredCommand tells the user that the search session behind a button is gone
+type SearchExpiredCommand struct {
+	environment.Env
+}
+
+func (cmd *SearchExpiredCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId: upd.CallbackQuery.Message.Chat.Id,
+		Text:   searchExpiredText,
+	})
 	return nil
 }
+
+// prepareSearchResultsPage renders one page of a search session
+func prepareSearchResultsPage(session *searchSession, token string, page int) (string, *telegram.InlineKeyboardMarkup) {
+	total := len(session.Items)
+	totalPages := (total + searchPageSize - 1) / searchPageSize
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * searchPageSize
+	end := min(start+searchPageSize, total)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Results for \"%s\":\n\n", session.Query)
+	for i, f := range session.Items[start:end] {
+		fmt.Fprintf(&sb, "%d. %v\nSize: %v%v, Seeders: %v\n\n", start+i+1, f.Title, f.Size.Size, f.Size.Unit, f.Seeders)
+	}
+	fmt.Fprintf(&sb, "Page %d/%d (total: %d)", page+1, totalPages, total)
+
+	var rows [][]telegram.InlineKeyboardButton
+	var downloadButtons []telegram.InlineKeyboardButton
+	for i := start; i < end; i++ {
+		downloadButtons = append(downloadButtons, telegram.InlineKeyboardButton{
+			Text:         fmt.Sprintf("⬇ %d", i+1),
+			CallbackData: fmt.Sprintf("/sdl %s %d", token, i),
+		})
+	}
+	rows = append(rows, downloadButtons)
+	if navButtons := buildNavButtons(page, totalPages, "/search_page "+token+" %d"); len(navButtons) > 0 {
+		rows = append(rows, navButtons)
+	}
+
+	return sb.String(), &telegram.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
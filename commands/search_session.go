@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/minya/rutracker"
+)
+
+const (
+	searchSessionTTL  = 2 * time.Hour
+	maxSearchSessions = 256
+)
+
+// searchSession keeps the results of one search so that pagination and
+// download callbacks only need to carry a short token and an index.
+type searchSession struct {
+	Query string
+	Items []rutracker.RutrackerSearchItem
+}
+
+// searchSessionStore is an in-memory store of search sessions keyed by token.
+type searchSessionStore = tokenStore[*searchSession]
+
+func newSearchSessionStore(ttl time.Duration, max int) *searchSessionStore {
+	return newTokenStore[*searchSession](ttl, max)
+}
+
+// searchSessions is shared by the search, search page and search download commands.
+var searchSessions = newSearchSessionStore(searchSessionTTL, maxSearchSessions)
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minya/rutracker"
+)
+
+func TestSearchSessionStorePutGet(t *testing.T) {
+	store := newSearchSessionStore(time.Hour, 10)
+	items := []rutracker.RutrackerSearchItem{{Title: "a"}, {Title: "b"}}
+
+	token, err := store.Put(&searchSession{Query: "query", Items: items})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(token) != 2*tokenLength {
+		t.Fatalf("unexpected token length: %q", token)
+	}
+
+	session, ok := store.Get(token)
+	if !ok {
+		t.Fatal("expected session to be found")
+	}
+	if session.Query != "query" || len(session.Items) != 2 {
+		t.Fatalf("unexpected session: %#v", session)
+	}
+	if _, ok := store.Get("unknown"); ok {
+		t.Fatal("expected no session for unknown token")
+	}
+}
+
+func TestSearchSessionStoreExpires(t *testing.T) {
+	store := newSearchSessionStore(time.Hour, 10)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	token, _ := store.Put(&searchSession{Query: "query"})
+	now = now.Add(2 * time.Hour)
+
+	if _, ok := store.Get(token); ok {
+		t.Fatal("expected session to expire")
+	}
+}
+
+func TestSearchSessionStoreEvictsOldest(t *testing.T) {
+	store := newSearchSessionStore(time.Hour, 2)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	first, _ := store.Put(&searchSession{Query: "first"})
+	now = now.Add(time.Minute)
+	second, _ := store.Put(&searchSession{Query: "second"})
+	now = now.Add(time.Minute)
+	third, _ := store.Put(&searchSession{Query: "third"})
+
+	if _, ok := store.Get(first); ok {
+		t.Fatal("expected oldest session to be evicted")
+	}
+	_, secondOK := store.Get(second)
+	_, thirdOK := store.Get(third)
+	if !secondOK || !thirdOK {
+		t.Fatal("expected newer sessions to be kept")
+	}
+}
@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/preferences"
+)
+
+// Settings that can be changed from the /settings menu
+const (
+	settingCompleted = "completed"
+	settingAdded     = "added"
+	settingStalled   = "stalled"
+	settingDigest    = "digest"
+	settingQuiet     = "quiet"
+	settingSilent    = "silent"
+)
+
+// SettingsCommand shows the notification settings menu, or changes one
+// setting when triggered from its button: /settings, /set <setting>
+type SettingsCommand struct {
+	Setting string
+	environment.Env
+}
+
+type SettingsCommandFactory struct {
+	environment.Env
+}
+
+var (
+	reSettingsCmd = regexp.MustCompile(`^/settings\s*$`)
+	reSetCmd      = regexp.MustCompile(`^/set\s+(completed|added|stalled|digest|quiet|silent)$`)
+)
+
+func (factory *SettingsCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil {
+		return false, nil
+	}
+	if upd.Message != nil && reSettingsCmd.MatchString(upd.Message.Text) {
+		return true, &SettingsCommand{Env: factory.Env}
+	}
+	if upd.CallbackQuery != nil {
+		if found := reSetCmd.FindStringSubmatch(upd.CallbackQuery.Data); found != nil {
+			return true, &SettingsCommand{Setting: found[1], Env: factory.Env}
+		}
+	}
+	return false, nil
+}
+
+func (cmd *SettingsCommand) Handle(upd *telegram.Update) error {
+	if upd.CallbackQuery != nil {
+		return cmd.handleToggle(upd)
+	}
+
+	chatID := upd.Message.Chat.Id
+	if cmd.Preferences == nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "Settings are not configured on this bot",
+		})
+		return nil
+	}
+	if upd.Message.From == nil {
+		return nil
+	}
+
+	prefs := cmd.Preferences.Get(upd.Message.From.Id)
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId:      chatID,
+		Text:        FormatSettings(prefs, cmd.DigestTime),
+		ReplyMarkup: BuildSettingsKeyboard(prefs, cmd.DigestTime != ""),
+	})
+	return nil
+}
+
+func (cmd *SettingsCommand) handleToggle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	if cmd.Preferences == nil || upd.CallbackQuery.From == nil || upd.CallbackQuery.Message == nil {
+		return nil
+	}
+	chatID := upd.CallbackQuery.Message.Chat.Id
+	messageID := upd.CallbackQuery.Message.MessageId
+
+	prefs, err := cmd.Preferences.Update(upd.CallbackQuery.From.Id, func(p *preferences.Preferences) {
+		applySetting(p, cmd.Setting)
+	})
+	if err != nil {
+		logger.Error(err, "Error saving preferences")
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "Failed to save your settings, please try again",
+		})
+		return err
+	}
+
+	keyboard := BuildSettingsKeyboard(prefs, cmd.DigestTime != "")
+	cmd.TgApi.EditMessageText(&telegram.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        FormatSettings(prefs, cmd.DigestTime),
+		ReplyMarkup: &keyboard,
+	})
+	return nil
+}
+
+// applySetting flips a setting; quiet hours cycle through the presets and back to off.
+func applySetting(p *preferences.Preferences, setting string) {
+	switch setting {
+	case settingCompleted:
+		p.Completed = !p.Completed
+	case settingAdded:
+		p.Added = !p.Added
+	case settingStalled:
+		p.Stalled = !p.Stalled
+	case settingDigest:
+		p.Digest = !p.Digest
+	case settingSilent:
+		p.Silent = !p.Silent
+	case settingQuiet:
+		p.QuietHours = nextQuietHours(p.QuietHours)
+	}
+}
+
+func nextQuietHours(current *preferences.QuietHours) *preferences.QuietHours {
+	presets := preferences.QuietHoursPresets
+	if current == nil {
+		next := presets[0]
+		return &next
+	}
+	for i, preset := range presets {
+		if preset == *current && i+1 < len(presets) {
+			next := presets[i+1]
+			return &next
+		}
+	}
+	return nil
+}
+
+// FormatSettings describes the current notification settings.
+func FormatSettings(prefs preferences.Preferences, digestTime string) string {
+	var sb strings.Builder
+	sb.WriteString("Notification settings\n")
+	fmt.Fprintf(&sb, "\nCompleted downloads: %s", onOff(prefs.Completed))
+	fmt.Fprintf(&sb, "\nAdded torrents and live progress: %s", onOff(prefs.Added))
+	fmt.Fprintf(&sb, "\nStalled and errored torrents: %s", onOff(prefs.Stalled))
+	if digestTime != "" {
+		fmt.Fprintf(&sb, "\nDaily digest at %s: %s", digestTime, onOff(prefs.Digest))
+	}
+	if prefs.QuietHours != nil {
+		fmt.Fprintf(&sb, "\nQuiet hours: %s", prefs.QuietHours)
+	} else {
+		sb.WriteString("\nQuiet hours: off")
+	}
+	if prefs.Silent {
+		sb.WriteString("\nAll messages are delivered without sound")
+	} else if prefs.QuietHours != nil {
+		sb.WriteString("\nMessages during quiet hours are delivered without sound")
+	}
+	return sb.String()
+}
+
+// BuildSettingsKeyboard has one button per setting; the digest button is
+// left out when the digest is not configured.
+func BuildSettingsKeyboard(prefs preferences.Preferences, digest bool) telegram.InlineKeyboardMarkup {
+	toggle := func(label string, on bool, setting string) telegram.InlineKeyboardButton {
+		mark := "⬜"
+		if on {
+			mark = "✅"
+		}
+		return telegram.InlineKeyboardButton{
+			Text:         fmt.Sprintf("%s %s", mark, label),
+			CallbackData: "/set " + setting,
+		}
+	}
+
+	quiet := "🌙 Quiet hours: off"
+	if prefs.QuietHours != nil {
+		quiet = fmt.Sprintf("🌙 Quiet hours: %s", prefs.QuietHours)
+	}
+	sound := "🔔 Sound on"
+	if prefs.Silent {
+		sound = "🔕 Silent"
+	}
+
+	events := []telegram.InlineKeyboardButton{toggle("Stalled", prefs.Stalled, settingStalled)}
+	if digest {
+		events = append(events, toggle("Digest", prefs.Digest, settingDigest))
+	}
+
+	return telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{toggle("Completed", prefs.Completed, settingCompleted), toggle("Added", prefs.Added, settingAdded)},
+			events,
+			{{Text: quiet, CallbackData: "/set " + settingQuiet}},
+			{{Text: sound, CallbackData: "/set " + settingSilent}},
+		},
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/preferences"
+)
+
+func TestSettingsCommandFactory(t *testing.T) {
+	factory := SettingsCommandFactory{}
+
+	ok, cmd := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/settings"}})
+	if !ok || cmd.(*SettingsCommand).Setting != "" {
+		t.Errorf("expected settings menu, got %v %#v", ok, cmd)
+	}
+	ok, cmd = factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/set quiet"}})
+	if !ok || cmd.(*SettingsCommand).Setting != settingQuiet {
+		t.Errorf("expected quiet hours toggle, got %v %#v", ok, cmd)
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/set volume"}}); ok {
+		t.Error("expected factory to reject unknown setting")
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/set silent"}}); ok {
+		t.Error("expected toggles to be accepted only from buttons")
+	}
+}
+
+func TestApplySetting(t *testing.T) {
+	prefs := preferences.Default()
+	applySetting(&prefs, settingCompleted)
+	applySetting(&prefs, settingSilent)
+	if prefs.Completed || !prefs.Silent {
+		t.Errorf("expected completed off and silent on, got %#v", prefs)
+	}
+
+	// Quiet hours cycle through every preset and back to off
+	for _, preset := range preferences.QuietHoursPresets {
+		applySetting(&prefs, settingQuiet)
+		if prefs.QuietHours == nil || *prefs.QuietHours != preset {
+			t.Fatalf("expected quiet hours %v, got %v", preset, prefs.QuietHours)
+		}
+	}
+	applySetting(&prefs, settingQuiet)
+	if prefs.QuietHours != nil {
+		t.Errorf("expected quiet hours off after the last preset, got %v", prefs.QuietHours)
+	}
+}
+
+func TestBuildSettingsKeyboard(t *testing.T) {
+	prefs := preferences.Default()
+	prefs.QuietHours = &preferences.QuietHours{From: 22, To: 8}
+
+	keyboard := BuildSettingsKeyboard(prefs, false)
+	var labels []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			labels = append(labels, button.Text)
+			if len(button.CallbackData) > 64 {
+				t.Errorf("callback data too long: %q", button.CallbackData)
+			}
+		}
+	}
+	joined := strings.Join(labels, "|")
+	for _, want := range []string{"✅ Completed", "✅ Added", "✅ Stalled", "22:00–08:00", "🔔 Sound on"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in %q", want, joined)
+		}
+	}
+	if strings.Contains(joined, "Digest") {
+		t.Error("expected no digest button when the digest is not configured")
+	}
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const tokenLength = 4 // bytes, 8 hex characters
+
+// tokenStore keeps values server-side so that callback data only needs to
+// carry a short token instead of the value itself, which keeps it under
+// Telegram's 64-byte callback data limit.
+type tokenStore[T any] struct {
+	mu      sync.Mutex
+	entries map[string]tokenEntry[T]
+	ttl     time.Duration
+	max     int
+	now     func() time.Time
+}
+
+type tokenEntry[T any] struct {
+	value   T
+	created time.Time
+}
+
+func newTokenStore[T any](ttl time.Duration, max int) *tokenStore[T] {
+	return &tokenStore[T]{
+		entries: make(map[string]tokenEntry[T]),
+		ttl:     ttl,
+		max:     max,
+		now:     time.Now,
+	}
+}
+
+// Put stores the value and returns the token referencing it.
+func (s *tokenStore[T]) Put(value T) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictLocked()
+	s.entries[token] = tokenEntry[T]{value: value, created: s.now()}
+	return token, nil
+}
+
+// Get returns the value for the token, or false if it is unknown or expired.
+func (s *tokenStore[T]) Get(token string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[token]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	if s.now().Sub(entry.created) > s.ttl {
+		delete(s.entries, token)
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// evictLocked drops expired entries and, if the store is still full, the oldest one.
+func (s *tokenStore[T]) evictLocked() {
+	now := s.now()
+	for token, entry := range s.entries {
+		if now.Sub(entry.created) > s.ttl {
+			delete(s.entries, token)
+		}
+	}
+	for len(s.entries) >= s.max {
+		var oldestToken string
+		var oldest time.Time
+		for token, entry := range s.entries {
+			if oldestToken == "" || entry.created.Before(oldest) {
+				oldestToken = token
+				oldest = entry.created
+			}
+		}
+		delete(s.entries, oldestToken)
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
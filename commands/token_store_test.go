@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenStorePutGet(t *testing.T) {
+	store := newTokenStore[string](time.Hour, 10)
+
+	token, err := store.Put("value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(token) != 2*tokenLength {
+		t.Fatalf("unexpected token length: %q", token)
+	}
+
+	value, ok := store.Get(token)
+	if !ok || value != "value" {
+		t.Fatalf("unexpected value: %q, %v", value, ok)
+	}
+	if _, ok := store.Get("unknown"); ok {
+		t.Fatal("expected unknown token to be missing")
+	}
+}
+
+func TestTokenStoreExpires(t *testing.T) {
+	store := newTokenStore[string](time.Hour, 10)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	token, _ := store.Put("value")
+	now = now.Add(2 * time.Hour)
+
+	if _, ok := store.Get(token); ok {
+		t.Fatal("expected entry to expire")
+	}
+}
+
+func TestTokenStoreEvictsOldest(t *testing.T) {
+	store := newTokenStore[string](time.Hour, 2)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	first, _ := store.Put("first")
+	now = now.Add(time.Minute)
+	second, _ := store.Put("second")
+	now = now.Add(time.Minute)
+	third, _ := store.Put("third")
+
+	if _, ok := store.Get(first); ok {
+		t.Fatal("expected oldest entry to be evicted")
+	}
+	if _, ok := store.Get(second); !ok {
+		t.Fatal("expected second entry to be kept")
+	}
+	if _, ok := store.Get(third); !ok {
+		t.Fatal("expected third entry to be kept")
+	}
+}
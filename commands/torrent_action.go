@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+)
+
+// Actions available from the /info keyboard
+const (
+	actionPause      = "pause"
+	actionResume     = "resume"
+	actionVerify     = "verify"
+	actionReannounce = "reannounce"
+	actionRefresh    = "refresh"
+)
+
+// actionMethods maps an action to its Transmission RPC method; refresh has none.
+var actionMethods = map[string]string{
+	actionPause:      rpcTorrentStop,
+	actionResume:     rpcTorrentStart,
+	actionVerify:     rpcTorrentVerify,
+	actionReannounce: rpcTorrentReannounce,
+	actionRefresh:    "",
+}
+
+// TorrentActionCommand handles action buttons under the /info view
+type TorrentActionCommand struct {
+	Action    string
+	TorrentID int
+	environment.Env
+}
+
+type TorrentActionCommandFactory struct {
+	environment.Env
+}
+
+var reTorrentActionCmd = regexp.MustCompile(`^/ta\s+(\w+)\s+(\d+)$`)
+
+func (f *TorrentActionCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	found := reTorrentActionCmd.FindStringSubmatch(upd.CallbackQuery.Data)
+	if len(found) != 3 {
+		return false, nil
+	}
+	if _, ok := actionMethods[found[1]]; !ok {
+		logger.Error(nil, "Invalid torrent action: %s", found[1])
+		return false, nil
+	}
+	torrentID, err := strconv.Atoi(found[2])
+	if err != nil {
+		return false, nil
+	}
+	return true, &TorrentActionCommand{
+		Action:    found[1],
+		TorrentID: torrentID,
+		Env:       f.Env,
+	}
+}
+
+func (cmd *TorrentActionCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+	messageID := upd.CallbackQuery.Message.MessageId
+
+	torrent, err := findOwnedTorrent(cmd.TransmissionClient, cmd.TorrentID, chatID)
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		return err
+	}
+	if torrent == nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   fmt.Sprintf("Torrent %d not found", cmd.TorrentID),
+			ChatId: chatID,
+		})
+		return nil
+	}
+
+	if method := actionMethods[cmd.Action]; method != "" {
+		logger.Info("Torrent action %s on torrent %d", cmd.Action, cmd.TorrentID)
+		if err := torrentAction(cmd.TransmissionClient, method, []int{cmd.TorrentID}); err != nil {
+			logger.Error(err, "Error running torrent action %s", cmd.Action)
+			cmd.TgApi.SendMessage(telegram.ReplyMessage{
+				Text:   "Error",
+				ChatId: chatID,
+			})
+			return err
+		}
+	}
+
+	return editTorrentInfo(cmd.Env, chatID, messageID, cmd.TorrentID)
+}
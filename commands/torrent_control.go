@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/odwrtw/transmission"
+)
+
+// TorrentControlCommand handles /pause, /resume, /verify and /reannounce for
+// one of the caller's torrents, or for all of them with /pause all and /resume all.
+type TorrentControlCommand struct {
+	Action    string
+	TorrentID int
+	All       bool
+	environment.Env
+}
+
+type TorrentControlCommandFactory struct {
+	environment.Env
+}
+
+var reTorrentControlCmd = regexp.MustCompile(`^/(pause|resume|verify|reannounce)\s+(\d+|all)\s*?$`)
+
+// actionPastTense is used in replies, e.g. "Paused: 12 Name".
+var actionPastTense = map[string]string{
+	actionPause:      "Paused",
+	actionResume:     "Resumed",
+	actionVerify:     "Verification started",
+	actionReannounce: "Reannounced",
+}
+
+func (f *TorrentControlCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil {
+		return false, nil
+	}
+	found := reTorrentControlCmd.FindStringSubmatch(commandText(upd))
+	if len(found) != 3 {
+		return false, nil
+	}
+	cmd := &TorrentControlCommand{Action: found[1], Env: f.Env}
+	if found[2] == "all" {
+		cmd.All = true
+	} else {
+		torrentID, err := strconv.Atoi(found[2])
+		if err != nil {
+			return false, nil
+		}
+		cmd.TorrentID = torrentID
+	}
+	return true, cmd
+}
+
+func (cmd *TorrentControlCommand) Handle(upd *telegram.Update) error {
+	chatID := replyChatID(upd, cmd.TgApi)
+	reply := func(text string) {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			Text:   text,
+			ChatId: chatID,
+		})
+	}
+
+	if cmd.All && cmd.Action != actionPause && cmd.Action != actionResume {
+		reply(fmt.Sprintf("Usage: /%s <id>", cmd.Action))
+		return nil
+	}
+
+	allTorrents, err := cmd.TransmissionClient.GetTorrents()
+	if err != nil {
+		logger.Error(err, "Error getting torrents")
+		reply("Error")
+		return err
+	}
+	targets := selectControlTargets(ownedTorrents(allTorrents, chatID), cmd.Action, cmd.TorrentID, cmd.All)
+
+	if len(targets) == 0 {
+		if cmd.All {
+			reply("Nothing to " + cmd.Action)
+		} else {
+			reply(fmt.Sprintf("Torrent %d not found", cmd.TorrentID))
+		}
+		return nil
+	}
+
+	ids := make([]int, len(targets))
+	for i, torrent := range targets {
+		ids[i] = torrent.ID
+	}
+	logger.Info("Torrent action %s on torrents %v for chat %d", cmd.Action, ids, chatID)
+	if err := torrentAction(cmd.TransmissionClient, actionMethods[cmd.Action], ids); err != nil {
+		logger.Error(err, "Error running torrent action %s", cmd.Action)
+		reply("Error")
+		return err
+	}
+
+	reply(formatControlResult(cmd.Action, targets))
+	return nil
+}
+
+// selectControlTargets picks the torrents an action applies to from the caller's torrents.
+// "all" skips torrents the action would not change.
+func selectControlTargets(owned []*transmission.Torrent, action string, torrentID int, all bool) []*transmission.Torrent {
+	var targets []*transmission.Torrent
+	for _, torrent := range owned {
+		switch {
+		case !all:
+			if torrent.ID == torrentID {
+				targets = append(targets, torrent)
+			}
+		case action == actionPause && torrent.Status != transmission.StatusStopped:
+			targets = append(targets, torrent)
+		case action == actionResume && torrent.Status == transmission.StatusStopped:
+			targets = append(targets, torrent)
+		}
+	}
+	return targets
+}
+
+func formatControlResult(action string, targets []*transmission.Torrent) string {
+	if len(targets) == 1 {
+		return fmt.Sprintf("%s: %d %s", actionPastTense[action], targets[0].ID, targets[0].Name)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %d torrents:\n", actionPastTense[action], len(targets))
+	for _, torrent := range targets {
+		fmt.Fprintf(&sb, "%d %s\n", torrent.ID, torrent.Name)
+	}
+	return sb.String()
+}
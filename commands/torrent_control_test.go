@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/minya/telegram"
+	"github.com/odwrtw/transmission"
+)
+
+func TestTorrentControlCommandFactory(t *testing.T) {
+	factory := TorrentControlCommandFactory{}
+
+	cases := []struct {
+		text   string
+		action string
+		id     int
+		all    bool
+	}{
+		{"/pause 12", actionPause, 12, false},
+		{"/resume all", actionResume, 0, true},
+		{"/verify 3", actionVerify, 3, false},
+		{"/reannounce 4 ", actionReannounce, 4, false},
+	}
+	for _, c := range cases {
+		ok, cmd := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: c.text}})
+		if !ok {
+			t.Fatalf("expected factory to accept %q", c.text)
+		}
+		controlCmd := cmd.(*TorrentControlCommand)
+		if controlCmd.Action != c.action || controlCmd.TorrentID != c.id || controlCmd.All != c.all {
+			t.Errorf("%q: unexpected command %#v", c.text, controlCmd)
+		}
+	}
+
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "/pause"}}); ok {
+		t.Error("expected factory to reject /pause without argument")
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{CallbackQuery: &telegram.CallbackQuery{Data: "/resume 5"}}); !ok {
+		t.Error("expected factory to accept callback")
+	}
+}
+
+func TestSelectControlTargetsRespectsOwnership(t *testing.T) {
+	torrents := []*transmission.Torrent{
+		{ID: 1, Labels: []string{"111", "movies"}, Status: transmission.StatusDownloading},
+		{ID: 2, Labels: []string{"222", "movies"}, Status: transmission.StatusDownloading},
+		{ID: 3, Labels: []string{"111", "shows"}, Status: transmission.StatusStopped},
+		{ID: 4},
+	}
+	owned := ownedTorrents(torrents, 111)
+
+	if targets := selectControlTargets(owned, actionPause, 2, false); len(targets) != 0 {
+		t.Errorf("expected another user's torrent to be skipped, got %v", targets)
+	}
+	if targets := selectControlTargets(owned, actionPause, 1, false); len(targets) != 1 || targets[0].ID != 1 {
+		t.Errorf("expected torrent 1, got %v", targets)
+	}
+	if targets := selectControlTargets(owned, actionPause, 0, true); len(targets) != 1 || targets[0].ID != 1 {
+		t.Errorf("expected pause all to select running torrent 1 only, got %v", targets)
+	}
+	if targets := selectControlTargets(owned, actionResume, 0, true); len(targets) != 1 || targets[0].ID != 3 {
+		t.Errorf("expected resume all to select paused torrent 3 only, got %v", targets)
+	}
+}
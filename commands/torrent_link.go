@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/telegram"
+	"github.com/minya/tgtorrentbot/bencode"
+	"github.com/minya/tgtorrentbot/environment"
+	"github.com/minya/tgtorrentbot/torrentlink"
+)
+
+const (
+	pendingTorrentURLTTL  = time.Hour
+	maxPendingTorrentURLs = 256
+)
+
+// pendingTorrentURLs keeps direct .torrent URLs between the category prompt and
+// the category choice; they may not fit into callback data.
+var pendingTorrentURLs = newTokenStore[string](pendingTorrentURLTTL, maxPendingTorrentURLs)
+
+var reLinkText = regexp.MustCompile(`^(?i)(https?://\S+|(dl|viewtopic)\.php\?\S+)$`)
+
+// TorrentLinkCommand asks for a category for a pasted Rutracker or .torrent link
+type TorrentLinkCommand struct {
+	Text string
+	environment.Env
+}
+
+type TorrentLinkCommandFactory struct {
+	environment.Env
+}
+
+func (factory *TorrentLinkCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.Message == nil {
+		return false, nil
+	}
+	text := strings.TrimSpace(upd.Message.Text)
+	if reLinkText.MatchString(text) {
+		return true, &TorrentLinkCommand{
+			Text: text,
+			Env:  factory.Env,
+		}
+	}
+	return false, nil
+}
+
+func (cmd *TorrentLinkCommand) Handle(upd *telegram.Update) error {
+	chatID := upd.Message.Chat.Id
+
+	link, err := torrentlink.Resolve(cmd.Text, cmd.TrustedTorrentHosts)
+	if err != nil {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   fmt.Sprintf("Can't download this link: %v", err),
+		})
+		return nil
+	}
+
+	var text string
+	var keyboard telegram.InlineKeyboardMarkup
+	switch link.Kind {
+	case torrentlink.KindRutracker:
+		text = fmt.Sprintf("Rutracker topic %s\n\nSelect category:", link.TopicID)
+		keyboard = buildCategoryKeyboard("/dlcat", link.URL)
+	default:
+		token, err := pendingTorrentURLs.Put(link.URL)
+		if err != nil {
+			logger.Error(err, "Error storing torrent URL")
+			return err
+		}
+		text = fmt.Sprintf("Torrent: %s\n\nSelect category:", link.URL)
+		keyboard = buildCategoryKeyboard("/dlurl", token)
+	}
+
+	cmd.TgApi.SendMessage(telegram.ReplyMessage{
+		ChatId:      chatID,
+		Text:        text,
+		ReplyMarkup: keyboard,
+	})
+	return nil
+}
+
+// DownloadURLWithCategoryCommand fetches a pending .torrent URL and adds it with the chosen category
+type DownloadURLWithCategoryCommand struct {
+	Token    string
+	Category Category
+	environment.Env
+}
+
+type DownloadURLWithCategoryCommandFactory struct {
+	environment.Env
+}
+
+var reDownloadURLWithCategoryCmd = regexp.MustCompile(`^/dlurl\s+(\S+)\s+([0-9a-f]+)$`)
+
+func (factory *DownloadURLWithCategoryCommandFactory) Accepts(upd *telegram.Update) (bool, Command) {
+	if upd == nil || upd.CallbackQuery == nil {
+		return false, nil
+	}
+	if found := reDownloadURLWithCategoryCmd.FindStringSubmatch(upd.CallbackQuery.Data); len(found) == 3 {
+		categoryStr := strings.TrimSpace(found[1])
+
+		category, ok := ParseCategory(categoryStr)
+		if !ok {
+			logger.Error(nil, "Invalid category: %s", categoryStr)
+			return false, nil
+		}
+
+		return true, &DownloadURLWithCategoryCommand{
+			Token:    found[2],
+			Category: category,
+			Env:      factory.Env,
+		}
+	}
+	return false, nil
+}
+
+func (cmd *DownloadURLWithCategoryCommand) Handle(upd *telegram.Update) error {
+	AnswerCallbackQuery(upd, cmd.TgApi)
+	chatID := upd.CallbackQuery.Message.Chat.Id
+
+	url, ok := pendingTorrentURLs.Get(cmd.Token)
+	if !ok {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "Link expired, please send it again",
+		})
+		return nil
+	}
+
+	// Re-resolve so a link stored before the allowlist changed is not fetched.
+	link, err := torrentlink.Resolve(url, cmd.TrustedTorrentHosts)
+	if err != nil || link.Kind != torrentlink.KindDirect {
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   fmt.Sprintf("Can't download this link: %v", err),
+		})
+		return nil
+	}
+
+	content, err := torrentlink.Fetch(link, cmd.TrustedTorrentHosts)
+	if err != nil {
+		logger.Error(err, "Error fetching torrent file")
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   "Failed to download the torrent file",
+		})
+		return nil
+	}
+
+	if _, err := bencode.ParseTorrent(content); err != nil {
+		logger.Warn("Rejected %s: %v", link.URL, err)
+		cmd.TgApi.SendMessage(telegram.ReplyMessage{
+			ChatId: chatID,
+			Text:   fmt.Sprintf("The link did not return a valid torrent: %v", err),
+		})
+		return nil
+	}
+
+	downloadCmd := &DownloadCommand{URL: link.URL, Env: cmd.Env}
+	return downloadCmd.addTorrentAndReply(content, chatID, cmd.Category)
+}
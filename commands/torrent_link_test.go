@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/minya/telegram"
+)
+
+func TestTorrentLinkCommandFactory(t *testing.T) {
+	factory := TorrentLinkCommandFactory{}
+	for _, text := range []string{
+		"https://rutracker.org/forum/viewtopic.php?t=123",
+		"dl.php?t=123",
+		"https://example.org/show.torrent",
+	} {
+		if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: text}}); !ok {
+			t.Errorf("expected factory to accept %q", text)
+		}
+	}
+	if ok, _ := factory.Accepts(&telegram.Update{Message: &telegram.Message{Text: "dune https://example.org"}}); ok {
+		t.Error("expected factory to reject text with a link inside")
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/odwrtw/transmission"
+)
+
+// Transmission RPC methods that act on a list of torrent IDs.
+const (
+	rpcTorrentStart      = "torrent-start"
+	rpcTorrentStop       = "torrent-stop"
+	rpcTorrentVerify     = "torrent-verify"
+	rpcTorrentReannounce = "torrent-reannounce"
+)
+
+// torrentAction calls a Transmission RPC method for the given torrent IDs in
+// one request. The client's Torrent methods need a fetched *Torrent and send
+// one request each, so actions go through its low-level Do, which handles auth
+// and session IDs.
+func torrentAction(client *transmission.Client, method string, ids []int) error {
+	type arg struct {
+		Ids []int `json:"ids"`
+	}
+	body, err := json.Marshal(transmission.Request{
+		Method:    method,
+		Arguments: arg{Ids: ids},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, client.Address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("transmission: %s: got http error %s", method, resp.Status)
+	}
+
+	var rpcResp transmission.Response
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return err
+	}
+	if rpcResp.Result != "success" {
+		return fmt.Errorf("transmission: %s: request response %q", method, rpcResp.Result)
+	}
+	return nil
+}
+
+// findTorrent returns the torrent with the given ID, or nil if there is none.
+func findTorrent(client *transmission.Client, id int) (*transmission.Torrent, error) {
+	torrents, err := client.GetTorrents()
+	if err != nil {
+		return nil, err
+	}
+	for _, torrent := range torrents {
+		if torrent.ID == id {
+			return torrent, nil
+		}
+	}
+	return nil, nil
+}
+
+// isTorrentOwner reports whether the torrent was added from the given chat.
+// labels[0] is the chat ID, labels[1] is the category.
+func isTorrentOwner(torrent *transmission.Torrent, chatID int64) bool {
+	return len(torrent.Labels) > 0 && torrent.Labels[0] == strconv.FormatInt(chatID, 10)
+}
+
+// ownedTorrents returns the torrents that were added from the given chat.
+func ownedTorrents(torrents []*transmission.Torrent, chatID int64) []*transmission.Torrent {
+	var result []*transmission.Torrent
+	for _, torrent := range torrents {
+		if isTorrentOwner(torrent, chatID) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// findOwnedTorrent returns the torrent with the given ID if it belongs to the
+// chat, or nil otherwise, so that other users' torrents look nonexistent.
+func findOwnedTorrent(client *transmission.Client, id int, chatID int64) (*transmission.Torrent, error) {
+	torrent, err := findTorrent(client, id)
+	if err != nil || torrent == nil {
+		return nil, err
+	}
+	if !isTorrentOwner(torrent, chatID) {
+		return nil, nil
+	}
+	return torrent, nil
+}
@@ -0,0 +1,255 @@
+// Package completionhooks runs the post-completion hooks configured per category: local
+// executables that receive the torrent in environment variables, and HTTP
+// webhooks that receive it as a JSON POST.
+package completionhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyCategory is the category key of hooks that run for every torrent.
+const AnyCategory = "*"
+
+// DefaultTimeout applies to hooks without a timeout.
+const DefaultTimeout = 5 * time.Minute
+
+// maxOutput is how much of a failed command's output is kept for the report.
+const maxOutput = 500
+
+// Hook is either a command or a webhook URL.
+type Hook struct {
+	// Name identifies the hook in failure reports; defaults to the command or URL host.
+	Name string `json:"name,omitempty"`
+	// Command is the executable followed by its arguments.
+	Command []string `json:"command,omitempty"`
+	// URL receives a JSON POST of the Event.
+	URL string `json:"url,omitempty"`
+	// Timeout is a duration such as "30s"; DefaultTimeout when empty.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Config maps a category, or AnyCategory, to its hooks.
+type Config map[string][]Hook
+
+// Event is the completed torrent passed to hooks.
+type Event struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Hash        string `json:"hash"`
+	Category    string `json:"category"`
+	DownloadDir string `json:"downloadDir"`
+	Owner       int64  `json:"owner"`
+}
+
+// Path is where the torrent's data is on disk.
+func (e Event) Path() string {
+	return filepath.Join(e.DownloadDir, e.Name)
+}
+
+// environ returns the variables a command hook receives.
+func (e Event) environ() []string {
+	return []string{
+		"TGT_HOOK_ID=" + strconv.Itoa(e.ID),
+		"TGT_HOOK_NAME=" + e.Name,
+		"TGT_HOOK_HASH=" + e.Hash,
+		"TGT_HOOK_CATEGORY=" + e.Category,
+		"TGT_HOOK_DOWNLOAD_DIR=" + e.DownloadDir,
+		"TGT_HOOK_PATH=" + e.Path(),
+		"TGT_HOOK_OWNER=" + strconv.FormatInt(e.Owner, 10),
+	}
+}
+
+// Failure is a hook that did not succeed.
+type Failure struct {
+	Hook string
+	Err  error
+}
+
+// Runner runs the configured hooks.
+type Runner struct {
+	hooks    Config
+	timeouts map[*Hook]time.Duration
+	client   *http.Client
+}
+
+// New validates the configuration and reports every invalid hook at once.
+func New(config Config) (*Runner, error) {
+	runner := &Runner{
+		hooks:    config,
+		timeouts: map[*Hook]time.Duration{},
+		client:   &http.Client{},
+	}
+
+	var problems []string
+	for category, hooks := range config {
+		for i := range hooks {
+			hook := &hooks[i]
+			if err := hook.validate(); err != nil {
+				problems = append(problems, fmt.Sprintf("%s hook %d: %v", category, i+1, err))
+				continue
+			}
+			timeout := DefaultTimeout
+			if hook.Timeout != "" {
+				parsed, err := time.ParseDuration(hook.Timeout)
+				if err != nil || parsed <= 0 {
+					problems = append(problems, fmt.Sprintf("%s hook %d: invalid timeout %q", category, i+1, hook.Timeout))
+					continue
+				}
+				timeout = parsed
+			}
+			runner.timeouts[hook] = timeout
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid hooks: %v", problems)
+	}
+	return runner, nil
+}
+
+func (h Hook) validate() error {
+	switch {
+	case len(h.Command) > 0 && h.URL != "":
+		return fmt.Errorf("set either command or url, not both")
+	case len(h.Command) > 0:
+		if h.Command[0] == "" {
+			return fmt.Errorf("empty command")
+		}
+		return nil
+	case h.URL != "":
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url %q", h.URL)
+		}
+		return nil
+	default:
+		return fmt.Errorf("command or url is required")
+	}
+}
+
+func (h Hook) label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if len(h.Command) > 0 {
+		return filepath.Base(h.Command[0])
+	}
+	if u, err := url.Parse(h.URL); err == nil {
+		return u.Host
+	}
+	return h.URL
+}
+
+// Has reports whether any hook runs for the category.
+func (r *Runner) Has(category string) bool {
+	if r == nil {
+		return false
+	}
+	return len(r.hooks[category]) > 0 || len(r.hooks[AnyCategory]) > 0
+}
+
+// Run runs the hooks of the event's category and the hooks for any category
+// in parallel, each within its timeout, and returns the ones that failed.
+func (r *Runner) Run(ctx context.Context, event Event) []Failure {
+	if r == nil {
+		return nil
+	}
+
+	var selected []*Hook
+	for _, category := range []string{event.Category, AnyCategory} {
+		hooks := r.hooks[category]
+		for i := range hooks {
+			selected = append(selected, &hooks[i])
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []Failure
+		wg       sync.WaitGroup
+	)
+	for _, hook := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hookCtx, cancel := context.WithTimeout(ctx, r.timeouts[hook])
+			defer cancel()
+
+			var err error
+			if len(hook.Command) > 0 {
+				err = runCommand(hookCtx, hook.Command, event)
+			} else {
+				err = r.post(hookCtx, hook.URL, event)
+			}
+			if err != nil {
+				if hookCtx.Err() == context.DeadlineExceeded {
+					err = fmt.Errorf("timed out after %s", r.timeouts[hook])
+				}
+				mu.Lock()
+				failures = append(failures, Failure{Hook: hook.label(), Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failures
+}
+
+func runCommand(ctx context.Context, command []string, event Event) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), event.environ()...)
+	// Don't wait forever for children that keep the output open after a timeout
+	cmd.WaitDelay = 5 * time.Second
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if out := strings.TrimSpace(string(output)); out != "" {
+		if len(out) > maxOutput {
+			out = "…" + out[len(out)-maxOutput:]
+		}
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return err
+}
+
+func (r *Runner) post(ctx context.Context, hookURL string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// The URL may carry credentials, so report only the cause
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package completionhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testEvent = Event{
+	ID:          7,
+	Name:        "Movie",
+	Hash:        "c12f",
+	Category:    "movies",
+	DownloadDir: "/downloads/movies",
+	Owner:       42,
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{
+		"movies": {
+			{Command: []string{"true"}, URL: "http://example.com"},
+			{URL: "ftp://example.com"},
+			{Command: []string{"true"}, Timeout: "soon"},
+			{},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"hook 1", "hook 2", "hook 3", "hook 4"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+}
+
+func TestRun_Command(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	runner, err := New(Config{
+		"movies": {{Command: []string{"sh", "-c", `echo "$TGT_HOOK_NAME $TGT_HOOK_HASH $TGT_HOOK_OWNER $TGT_HOOK_PATH" > "$0"`, out}}},
+		"music":  {{Command: []string{"false"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if failures := runner.Run(context.Background(), testEvent); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "Movie c12f 42 /downloads/movies/Movie" {
+		t.Errorf("unexpected hook environment %q", got)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	runner, err := New(Config{
+		"movies":    {{Name: "broken", Command: []string{"sh", "-c", "echo oops; exit 3"}}},
+		AnyCategory: {{Command: []string{"sleep", "5"}, Timeout: "50ms"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failures := runner.Run(context.Background(), testEvent)
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %v", failures)
+	}
+	byHook := map[string]string{}
+	for _, failure := range failures {
+		byHook[failure.Hook] = failure.Err.Error()
+	}
+	if !strings.Contains(byHook["broken"], "oops") {
+		t.Errorf("expected command output in failure, got %q", byHook["broken"])
+	}
+	if !strings.Contains(byHook["sleep"], "timed out") {
+		t.Errorf("expected timeout failure, got %q", byHook["sleep"])
+	}
+}
+
+func TestRun_Webhook(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	runner, err := New(Config{"movies": {{URL: server.URL + "/ok"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failures := runner.Run(context.Background(), testEvent); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if got != testEvent {
+		t.Errorf("unexpected payload %#v", got)
+	}
+
+	runner, _ = New(Config{"movies": {{URL: server.URL + "/fail"}}})
+	failures := runner.Run(context.Background(), testEvent)
+	if len(failures) != 1 || !strings.Contains(failures[0].Err.Error(), "500") {
+		t.Errorf("expected a 500 failure, got %v", failures)
+	}
+}
+
+func TestHas(t *testing.T) {
+	var nilRunner *Runner
+	if nilRunner.Has("movies") || nilRunner.Run(context.Background(), testEvent) != nil {
+		t.Error("expected a nil runner to have no hooks")
+	}
+	runner, _ := New(Config{"movies": {{Command: []string{"true"}}}})
+	if !runner.Has("movies") || runner.Has("music") {
+		t.Error("unexpected Has result")
+	}
+}
@@ -0,0 +1,242 @@
+// Package config loads the settings of the bot and the Mini App. Each setting
+// can come from the JSON settings file, a TGT_* environment variable or a
+// command line flag; flags override the environment, which overrides the file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/minya/rutracker"
+)
+
+// How the bot receives updates from Telegram
+const (
+	// UpdateModeWebhook has Telegram post updates to WebHookURL; the default.
+	UpdateModeWebhook = "webhook"
+	// UpdateModePolling fetches updates with getUpdates and needs no public URL.
+	UpdateModePolling = "polling"
+)
+
+// Defaults of the webhook listener
+const (
+	DefaultWebhookPort = 80
+	DefaultWebhookPath = "/"
+)
+
+// Addresses the Prometheus metrics are served on when none is set; they differ
+// so the bot and the Mini App can run on one host.
+const (
+	DefaultMetricsAddress       = ":9090"
+	DefaultWebAppMetricsAddress = ":9092"
+)
+
+// reWebhookSecret is what Telegram accepts as a webhook secret token.
+var reWebhookSecret = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// DefaultPath is the settings file read when no other is given; unlike an
+// explicitly given file it may be missing.
+const DefaultPath = "settings.json"
+
+type Settings struct {
+	BotToken            string                  `json:"botToken"`
+	WebHookURL          string                  `json:"webHookURL"`
+	UpdateMode          string                  `json:"updateMode"`
+	WebhookSecret       string                  `json:"webhookSecret"`
+	WebhookAddress      string                  `json:"webhookAddress"`
+	WebhookPort         int                     `json:"webhookPort"`
+	WebhookPath         string                  `json:"webhookPath"`
+	WebhookTLSCert      string                  `json:"webhookTLSCert"`
+	WebhookTLSKey       string                  `json:"webhookTLSKey"`
+	DownloadPath        string                  `json:"downloadPath"`
+	IncompletePath      string                  `json:"incompletePath"`
+	TransmissionRPC     TransmissionRPCSettings `json:"transmissionRPC"`
+	RutrackerConfig     rutracker.Config        `json:"rutrackerConfig"`
+	LogLevel            string                  `json:"logLevel"`
+	WebAppURL           string                  `json:"webAppURL"`
+	AllowedUsers        []int64                 `json:"allowedUsers"`
+	AdminUsers          []int64                 `json:"adminUsers"`
+	TrustedTorrentHosts []string                `json:"trustedTorrentHosts"`
+	StateFile           string                  `json:"stateFile"`
+	UploadBatchWindow   string                  `json:"uploadBatchWindow"`
+	StallTimeout        string                  `json:"stallTimeout"`
+	PreferencesFile     string                  `json:"preferencesFile"`
+	DigestTime          string                  `json:"digestTime"`
+	// CompletionHooks and SeedingPolicies are JSON the bot parses itself.
+	CompletionHooks      json.RawMessage `json:"completionHooks"`
+	ExtractCategories    []string        `json:"extractCategories"`
+	JellyfinURL          string          `json:"jellyfinURL"`
+	JellyfinAPIKey       string          `json:"jellyfinAPIKey"`
+	JellyfinPublicURL    string          `json:"jellyfinPublicURL"`
+	JellyfinMediaPath    string          `json:"jellyfinMediaPath"`
+	SeedingPolicies      json.RawMessage `json:"seedingPolicies"`
+	MetricsAddress       string          `json:"metricsAddress"`
+	WebAppMetricsAddress string          `json:"webAppMetricsAddress"`
+}
+
+type TransmissionRPCSettings struct {
+	Address  string `json:"address"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Options describe what a binary needs from its settings.
+type Options struct {
+	// Flags are the command line values; the default settings file is read without them.
+	Flags *Flags
+	// Required are the environment variable names of the settings the binary can't run without.
+	Required []string
+	// ValidCategory checks the categories the settings refer to; nil skips the check.
+	ValidCategory func(string) bool
+}
+
+// Load layers the settings file, the environment and the flags, and validates
+// the result. Every problem found is reported in the one error.
+func Load(opts Options) (Settings, error) {
+	var settings Settings
+	var problems []string
+
+	path, explicit := DefaultPath, false
+	if opts.Flags != nil && opts.Flags.path != "" {
+		path, explicit = opts.Flags.path, true
+	}
+	if err := readFile(path, &settings); err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, f := range fields {
+		value, source, err := lookupEnv(f.env)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		// An empty flag clears the setting; an empty variable is ignored
+		flagValue, flagged := opts.Flags.value(f.flag)
+		if flagged {
+			value, source = flagValue, "-"+f.flag
+		}
+		if value == "" && !flagged {
+			continue
+		}
+		if err := f.set(&settings, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", source, err))
+		}
+	}
+
+	if settings.WebhookPort == 0 {
+		settings.WebhookPort = DefaultWebhookPort
+	}
+	if settings.WebhookPath == "" {
+		settings.WebhookPath = DefaultWebhookPath
+	}
+	// Host names are compared with the lowercase host of parsed URLs
+	for i, host := range settings.TrustedTorrentHosts {
+		settings.TrustedTorrentHosts[i] = strings.ToLower(host)
+	}
+
+	problems = append(problems, validate(settings, opts)...)
+	if len(problems) > 0 {
+		return settings, fmt.Errorf("config problems: %s", strings.Join(problems, "; "))
+	}
+	return settings, nil
+}
+
+func readFile(path string, settings *Settings) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("settings file: %w", err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return fmt.Errorf("settings file %s: %w", path, err)
+	}
+	return nil
+}
+
+// lookupEnv returns a variable's value, read from the file named by its _FILE
+// variable for Docker secrets, and where it came from.
+func lookupEnv(name string) (value string, source string, err error) {
+	value = strings.TrimSpace(os.Getenv(name))
+	secretPath := strings.TrimSpace(os.Getenv(name + "_FILE"))
+	if secretPath == "" {
+		return value, name, nil
+	}
+	if value != "" {
+		return "", "", fmt.Errorf("set either %s or %s_FILE, not both", name, name)
+	}
+	data, err := os.ReadFile(secretPath)
+	if err != nil {
+		return "", "", fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), name + "_FILE", nil
+}
+
+func validate(s Settings, opts Options) []string {
+	var problems []string
+	for _, name := range opts.Required {
+		f, ok := fieldByEnv(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown setting %s", name))
+			continue
+		}
+		// Polling needs no webhook URL
+		if name == "TGT_WEBHOOKURL" && s.UpdateMode == UpdateModePolling {
+			continue
+		}
+		if f.empty(&s) {
+			problems = append(problems, fmt.Sprintf("%s is not set (or %s in the settings file)", f.env, f.key))
+		}
+	}
+
+	if s.UpdateMode != "" && s.UpdateMode != UpdateModeWebhook && s.UpdateMode != UpdateModePolling {
+		problems = append(problems, fmt.Sprintf("unknown update mode %q, expected %s or %s", s.UpdateMode, UpdateModeWebhook, UpdateModePolling))
+	}
+	if s.WebhookSecret != "" && !reWebhookSecret.MatchString(s.WebhookSecret) {
+		problems = append(problems, "webhook secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	if s.WebhookPort < 1 || s.WebhookPort > 65535 {
+		problems = append(problems, fmt.Sprintf("invalid webhook port %d", s.WebhookPort))
+	}
+	if !strings.HasPrefix(s.WebhookPath, "/") {
+		problems = append(problems, fmt.Sprintf("webhook path %q must start with /", s.WebhookPath))
+	}
+	if (s.WebhookTLSCert == "") != (s.WebhookTLSKey == "") {
+		problems = append(problems, "set both the webhook TLS certificate and key, or neither")
+	}
+	// Left empty, each binary uses its own default
+	for _, address := range []string{s.MetricsAddress, s.WebAppMetricsAddress} {
+		if address == "" {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("invalid metrics address %q, expected host:port or :port", address))
+		}
+	}
+	if s.StallTimeout != "" {
+		if timeout, err := time.ParseDuration(s.StallTimeout); err != nil || timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("invalid stall timeout %q", s.StallTimeout))
+		}
+	}
+	if s.DigestTime != "" {
+		if _, err := time.Parse("15:04", s.DigestTime); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid digest time %q, expected HH:MM", s.DigestTime))
+		}
+	}
+
+	if opts.ValidCategory != nil {
+		for _, category := range s.ExtractCategories {
+			if !opts.ValidCategory(category) {
+				problems = append(problems, fmt.Sprintf("unknown extract category %q", category))
+			}
+		}
+	}
+	return problems
+}
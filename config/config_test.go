@@ -0,0 +1,241 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSettingsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeSettingsFile(t, `{
+		"botToken": "file-token",
+		"downloadPath": "/file/downloads",
+		"logLevel": "warn",
+		"allowedUsers": [1, 2],
+		"transmissionRPC": {"address": "http://file:9091"}
+	}`)
+	t.Setenv("TGT_DOWNLOADPATH", "/env/downloads")
+	t.Setenv("TGT_LOGLEVEL", "error")
+	t.Setenv("TGT_ALLOWED_USERS", "3")
+
+	settings, err := Load(Options{Flags: parseFlags(t, "-settings", path, "-log-level", "debug")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.BotToken != "file-token" || settings.TransmissionRPC.Address != "http://file:9091" {
+		t.Errorf("expected values only in the file to be kept, got %+v", settings)
+	}
+	if settings.DownloadPath != "/env/downloads" {
+		t.Errorf("expected the environment to override the file, got %q", settings.DownloadPath)
+	}
+	if len(settings.AllowedUsers) != 1 || settings.AllowedUsers[0] != 3 {
+		t.Errorf("expected the environment to replace the list, got %v", settings.AllowedUsers)
+	}
+	if settings.LogLevel != "debug" {
+		t.Errorf("expected the flag to override the environment, got %q", settings.LogLevel)
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("secret-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TGT_BOTTOKEN_FILE", secret)
+
+	settings, err := Load(Options{Required: []string{"TGT_BOTTOKEN"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.BotToken != "secret-token" {
+		t.Errorf("BotToken = %q, want %q", settings.BotToken, "secret-token")
+	}
+
+	t.Setenv("TGT_BOTTOKEN", "plain-token")
+	if _, err := Load(Options{}); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Errorf("expected an error for a variable set twice, got %v", err)
+	}
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	t.Setenv("TGT_ALLOWED_USERS", "alice")
+	t.Setenv("TGT_STALL_TIMEOUT", "soon")
+	t.Setenv("TGT_EXTRACT_CATEGORIES", "movies,games")
+	t.Setenv("TGT_COMPLETION_HOOKS", `{"movies": [`)
+
+	_, err := Load(Options{
+		Required:      []string{"TGT_BOTTOKEN", "TGT_DOWNLOADPATH"},
+		ValidCategory: func(category string) bool { return category == "movies" },
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"TGT_ALLOWED_USERS: invalid user IDs",
+		"TGT_COMPLETION_HOOKS: invalid JSON",
+		"TGT_BOTTOKEN is not set",
+		"TGT_DOWNLOADPATH is not set",
+		"invalid stall timeout",
+		"unknown extract category \"games\"",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+}
+
+func TestLoad_SettingsFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// The default file may be missing, a given one may not
+	if _, err := Load(Options{}); err != nil {
+		t.Errorf("unexpected error without the default file: %v", err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := Load(Options{Flags: parseFlags(t, "-settings", missing)}); err == nil {
+		t.Error("expected an error for a missing settings file")
+	}
+
+	if err := os.WriteFile(DefaultPath, []byte(`{"botToken": "default-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.BotToken != "default-file" {
+		t.Errorf("expected the default file to be read, got %q", settings.BotToken)
+	}
+
+	broken := writeSettingsFile(t, `{"botToken": `)
+	if _, err := Load(Options{Flags: parseFlags(t, "-settings", broken)}); err == nil {
+		t.Error("expected an error for a malformed settings file")
+	}
+}
+
+func TestLoad_TrustedHostsLowercased(t *testing.T) {
+	t.Setenv("TGT_TRUSTED_TORRENT_HOSTS", " Torrents.Example.org, ,other.org")
+
+	settings, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(settings.TrustedTorrentHosts) != 2 || settings.TrustedTorrentHosts[0] != "torrents.example.org" {
+		t.Errorf("unexpected hosts %v", settings.TrustedTorrentHosts)
+	}
+
+	t.Setenv("TGT_TRUSTED_TORRENT_HOSTS", "")
+	path := writeSettingsFile(t, `{"trustedTorrentHosts": ["Files.Example.org"]}`)
+	settings, err = Load(Options{Flags: parseFlags(t, "-settings", path)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(settings.TrustedTorrentHosts) != 1 || settings.TrustedTorrentHosts[0] != "files.example.org" {
+		t.Errorf("expected hosts from the settings file to be lowercased, got %v", settings.TrustedTorrentHosts)
+	}
+}
+
+func TestLoad_EmptyFlagClearsSetting(t *testing.T) {
+	path := writeSettingsFile(t, `{
+		"webAppURL": "https://app.example.org",
+		"adminUsers": [1],
+		"completionHooks": {"movies": [{"command": ["true"]}]}
+	}`)
+	t.Setenv("TGT_JELLYFIN_URL", "http://jellyfin:8096")
+
+	settings, err := Load(Options{Flags: parseFlags(t, "-settings", path,
+		"-webapp-url=", "-admin-users=", "-completion-hooks=", "-jellyfin-url=")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.WebAppURL != "" || settings.AdminUsers != nil || settings.CompletionHooks != nil || settings.JellyfinURL != "" {
+		t.Errorf("expected empty flags to clear the file and environment values, got %+v", settings)
+	}
+
+	// An empty variable leaves the file's value
+	t.Setenv("TGT_WEBAPP_URL", "")
+	settings, err = Load(Options{Flags: parseFlags(t, "-settings", path)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.WebAppURL != "https://app.example.org" {
+		t.Errorf("expected the file's value, got %q", settings.WebAppURL)
+	}
+}
+
+func TestLoad_UpdateMode(t *testing.T) {
+	required := Options{Required: []string{"TGT_WEBHOOKURL"}}
+	if _, err := Load(required); err == nil {
+		t.Error("expected the webhook URL to be required by default")
+	}
+
+	t.Setenv("TGT_UPDATE_MODE", UpdateModePolling)
+	if _, err := Load(required); err != nil {
+		t.Errorf("expected no webhook URL to be needed for polling, got %v", err)
+	}
+
+	t.Setenv("TGT_UPDATE_MODE", "push")
+	if _, err := Load(Options{}); err == nil || !strings.Contains(err.Error(), "unknown update mode") {
+		t.Errorf("expected an unknown update mode error, got %v", err)
+	}
+}
+
+func TestLoad_Webhook(t *testing.T) {
+	settings, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.WebhookPort != DefaultWebhookPort || settings.WebhookPath != DefaultWebhookPath {
+		t.Errorf("expected the default listener, got port %d and path %q", settings.WebhookPort, settings.WebhookPath)
+	}
+	if settings.MetricsAddress != "" || settings.WebAppMetricsAddress != "" {
+		t.Errorf("expected no metrics addresses, so each binary uses its default, got %q and %q", settings.MetricsAddress, settings.WebAppMetricsAddress)
+	}
+
+	t.Setenv("TGT_WEBHOOK_PORT", "8443")
+	t.Setenv("TGT_WEBHOOK_PATH", "/telegram")
+	settings, err = Load(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.WebhookPort != 8443 || settings.WebhookPath != "/telegram" {
+		t.Errorf("got port %d and path %q", settings.WebhookPort, settings.WebhookPath)
+	}
+
+	t.Setenv("TGT_WEBHOOK_SECRET", "not a token!")
+	t.Setenv("TGT_WEBHOOK_PORT", "https")
+	t.Setenv("TGT_WEBHOOK_PATH", "telegram")
+	t.Setenv("TGT_WEBHOOK_TLS_CERT", "/certs/bot.pem")
+	t.Setenv("TGT_METRICS_ADDRESS", "9090")
+	t.Setenv("TGT_WEBAPP_METRICS_ADDRESS", "localhost")
+	_, err = Load(Options{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"webhook secret", "TGT_WEBHOOK_PORT", "must start with /", "TLS certificate and key", `invalid metrics address "9090"`, `invalid metrics address "localhost"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+}
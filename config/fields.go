@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// field is a setting that can be given in the environment or on the command line.
+type field struct {
+	// env is the environment variable; env+"_FILE" names a file holding the value.
+	env string
+	// flag is the command line flag, without the dash.
+	flag string
+	// key is the setting's name in the settings file.
+	key   string
+	usage string
+	// ptr points to the setting in s.
+	ptr func(s *Settings) any
+}
+
+var fields = []field{
+	{"TGT_BOTTOKEN", "bot-token", "botToken", "Telegram bot token", func(s *Settings) any { return &s.BotToken }},
+	{"TGT_WEBHOOKURL", "webhook-url", "webHookURL", "Webhook URL for Telegram updates", func(s *Settings) any { return &s.WebHookURL }},
+	{"TGT_UPDATE_MODE", "update-mode", "updateMode", "How updates are received: webhook (default) or polling", func(s *Settings) any { return &s.UpdateMode }},
+	{"TGT_WEBHOOK_SECRET", "webhook-secret", "webhookSecret", "Secret token Telegram sends with webhook requests; random when empty", func(s *Settings) any { return &s.WebhookSecret }},
+	{"TGT_WEBHOOK_ADDRESS", "webhook-address", "webhookAddress", "Address the webhook listener binds to; all interfaces when empty", func(s *Settings) any { return &s.WebhookAddress }},
+	{"TGT_WEBHOOK_PORT", "webhook-port", "webhookPort", "Port of the webhook listener (default 80)", func(s *Settings) any { return &s.WebhookPort }},
+	{"TGT_WEBHOOK_PATH", "webhook-path", "webhookPath", "Path the webhook listener accepts updates on (default /)", func(s *Settings) any { return &s.WebhookPath }},
+	{"TGT_WEBHOOK_TLS_CERT", "webhook-tls-cert", "webhookTLSCert", "TLS certificate file of the webhook listener", func(s *Settings) any { return &s.WebhookTLSCert }},
+	{"TGT_WEBHOOK_TLS_KEY", "webhook-tls-key", "webhookTLSKey", "TLS key file of the webhook listener", func(s *Settings) any { return &s.WebhookTLSKey }},
+	{"TGT_DOWNLOADPATH", "download-path", "downloadPath", "Base path for downloads", func(s *Settings) any { return &s.DownloadPath }},
+	{"TGT_INCOMPLETE_PATH", "incomplete-path", "incompletePath", "Path of incomplete downloads", func(s *Settings) any { return &s.IncompletePath }},
+	{"TGT_RPC_ADDR", "rpc-addr", "transmissionRPC.address", "Transmission RPC address", func(s *Settings) any { return &s.TransmissionRPC.Address }},
+	{"TGT_RPC_USER", "rpc-user", "transmissionRPC.user", "Transmission RPC user", func(s *Settings) any { return &s.TransmissionRPC.User }},
+	{"TGT_RPC_PASSWORD", "rpc-password", "transmissionRPC.password", "Transmission RPC password", func(s *Settings) any { return &s.TransmissionRPC.Password }},
+	{"TGT_RUTRACKER_USERNAME", "rutracker-username", "rutrackerConfig.username", "Rutracker username", func(s *Settings) any { return &s.RutrackerConfig.Username }},
+	{"TGT_RUTRACKER_PASSWORD", "rutracker-password", "rutrackerConfig.password", "Rutracker password", func(s *Settings) any { return &s.RutrackerConfig.Password }},
+	{"TGT_LOGLEVEL", "log-level", "logLevel", "Log level (debug, info, warn, error)", func(s *Settings) any { return &s.LogLevel }},
+	{"TGT_WEBAPP_URL", "webapp-url", "webAppURL", "Mini App URL", func(s *Settings) any { return &s.WebAppURL }},
+	{"TGT_ALLOWED_USERS", "allowed-users", "allowedUsers", "Comma-separated IDs of the users allowed to use the bot", func(s *Settings) any { return &s.AllowedUsers }},
+	{"TGT_ADMIN_USERS", "admin-users", "adminUsers", "Comma-separated IDs of the users who see all torrents", func(s *Settings) any { return &s.AdminUsers }},
+	{"TGT_TRUSTED_TORRENT_HOSTS", "trusted-torrent-hosts", "trustedTorrentHosts", "Comma-separated hosts whose .torrent URLs are fetched", func(s *Settings) any { return &s.TrustedTorrentHosts }},
+	{"TGT_STATE_FILE", "state-file", "stateFile", "Path of the completion notifier state file", func(s *Settings) any { return &s.StateFile }},
+	{"TGT_UPLOAD_BATCH_WINDOW", "upload-batch-window", "uploadBatchWindow", "How long to wait for more .torrent documents before one category prompt", func(s *Settings) any { return &s.UploadBatchWindow }},
+	{"TGT_STALL_TIMEOUT", "stall-timeout", "stallTimeout", "How long a torrent may stall before its owner is alerted", func(s *Settings) any { return &s.StallTimeout }},
+	{"TGT_PREFERENCES_FILE", "preferences-file", "preferencesFile", "Path of the per-user preferences file", func(s *Settings) any { return &s.PreferencesFile }},
+	{"TGT_DIGEST_TIME", "digest-time", "digestTime", "Local time of day (HH:MM) of the daily digest", func(s *Settings) any { return &s.DigestTime }},
+	{"TGT_COMPLETION_HOOKS", "completion-hooks", "completionHooks", "Completion hooks per category (JSON)", func(s *Settings) any { return &s.CompletionHooks }},
+	{"TGT_EXTRACT_CATEGORIES", "extract-categories", "extractCategories", "Comma-separated categories whose archives are extracted", func(s *Settings) any { return &s.ExtractCategories }},
+	{"TGT_JELLYFIN_URL", "jellyfin-url", "jellyfinURL", "Jellyfin server URL", func(s *Settings) any { return &s.JellyfinURL }},
+	{"TGT_JELLYFIN_API_KEY", "jellyfin-api-key", "jellyfinAPIKey", "Jellyfin API key", func(s *Settings) any { return &s.JellyfinAPIKey }},
+	{"TGT_JELLYFIN_PUBLIC_URL", "jellyfin-public-url", "jellyfinPublicURL", "Jellyfin address users open", func(s *Settings) any { return &s.JellyfinPublicURL }},
+	{"TGT_JELLYFIN_MEDIA_PATH", "jellyfin-media-path", "jellyfinMediaPath", "Folder holding the category folders as Jellyfin sees it", func(s *Settings) any { return &s.JellyfinMediaPath }},
+	{"TGT_SEEDING_POLICIES", "seeding-policies", "seedingPolicies", "Seeding policies per category (JSON)", func(s *Settings) any { return &s.SeedingPolicies }},
+	{"TGT_METRICS_ADDRESS", "metrics-address", "metricsAddress", "Address the bot serves Prometheus metrics on at /metrics (default :9090)", func(s *Settings) any { return &s.MetricsAddress }},
+	{"TGT_WEBAPP_METRICS_ADDRESS", "webapp-metrics-address", "webAppMetricsAddress", "Address the Mini App serves Prometheus metrics on at /metrics (default :9092)", func(s *Settings) any { return &s.WebAppMetricsAddress }},
+}
+
+func fieldByEnv(name string) (field, bool) {
+	for _, f := range fields {
+		if f.env == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// set parses the value into the setting: lists are comma-separated and JSON
+// settings are checked and kept as given. An empty value clears the setting.
+func (f field) set(s *Settings, value string) error {
+	if value == "" {
+		reflect.ValueOf(f.ptr(s)).Elem().SetZero()
+		return nil
+	}
+	switch ptr := f.ptr(s).(type) {
+	case *string:
+		*ptr = value
+	case *[]string:
+		*ptr = parseList(value)
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*ptr = n
+	case *[]int64:
+		ids, err := parseIDs(value)
+		*ptr = ids
+		return err
+	case *json.RawMessage:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("invalid JSON")
+		}
+		*ptr = json.RawMessage(value)
+	}
+	return nil
+}
+
+func (f field) empty(s *Settings) bool {
+	value := reflect.ValueOf(f.ptr(s)).Elem()
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// parseList splits a comma-separated list, dropping empty entries.
+func parseList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// parseIDs parses a comma-separated list of Telegram user IDs.
+func parseIDs(s string) ([]int64, error) {
+	var result []int64
+	var invalid []string
+	for _, part := range parseList(s) {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			invalid = append(invalid, part)
+			continue
+		}
+		result = append(result, id)
+	}
+	if len(invalid) > 0 {
+		return result, fmt.Errorf("invalid user IDs: %v", invalid)
+	}
+	return result, nil
+}
+
+// Flags holds the settings given on the command line.
+type Flags struct {
+	path   string
+	values map[string]string
+}
+
+// RegisterFlags adds -settings, the path of the settings file, and a flag for
+// every setting that has an environment variable.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{values: map[string]string{}}
+	fs.Func("settings", fmt.Sprintf("Path to settings file (default %s, which may be missing)", DefaultPath), func(s string) error {
+		flags.path = s
+		return nil
+	})
+	for _, f := range fields {
+		fs.Func(f.flag, f.usage+" - overrides "+f.env, func(s string) error {
+			flags.values[f.flag] = strings.TrimSpace(s)
+			return nil
+		})
+	}
+	return flags
+}
+
+func (f *Flags) value(name string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	value, ok := f.values[name]
+	return value, ok
+}
@@ -1,18 +1,34 @@
 package environment
 
 import (
+	"time"
+
 	"github.com/minya/telegram"
 	"github.com/minya/rutracker"
+	"github.com/minya/tgtorrentbot/botapi"
+	"github.com/minya/tgtorrentbot/preferences"
 	"github.com/odwrtw/transmission"
 )
 
+// ProgressTracker remembers which message shows the live progress of a torrent.
+type ProgressTracker interface {
+	TrackProgress(hash string, chatID int64, messageID int64)
+}
+
 type Env struct {
-	TransmissionClient *transmission.Client
-	TgApi              *telegram.Api
-	DownloadPath       string
-	RutrackerConfig    *rutracker.Config
-	WebAppURL          string
-	AllowedUsers       []int64
+	TransmissionClient  *transmission.Client
+	TgApi               *telegram.Api
+	DownloadPath        string
+	RutrackerConfig     *rutracker.Config
+	WebAppURL           string
+	AllowedUsers        []int64
+	AdminUsers          []int64
+	TrustedTorrentHosts []string
+	UploadBatchWindow   time.Duration
+	BotAPI              *botapi.Client
+	Progress            ProgressTracker
+	Preferences         *preferences.Store
+	DigestTime          string
 }
 
 func Environment(
@@ -0,0 +1,281 @@
+// Package extract unpacks the RAR and ZIP archives of completed releases next
+// to the originals, which are left in place so the torrent keeps seeding.
+package extract
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+// MarkerFile is written into an extracted item's folder with the Result, so
+// later runs and the Mini App know the item was extracted.
+const MarkerFile = ".tgt-extracted.json"
+
+var (
+	// Volumes after the first of a "name.partN.rar" set
+	reRarNextPart = regexp.MustCompile(`(?i)\.part0*([2-9]|[1-9]\d+)\.rar$`)
+	reArchive     = regexp.MustCompile(`(?i)\.(rar|zip)$`)
+	// The ".part1" left in a first volume's name once the extension is removed
+	reRarPartSuffix = regexp.MustCompile(`(?i)\.part0*1$`)
+)
+
+// Result describes the extraction of one item.
+type Result struct {
+	// Archives are the extracted archives relative to the item, first volumes only.
+	Archives []string  `json:"archives"`
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+	Failed   []Failure `json:"failed,omitempty"`
+	// Source is the archive a single-archive item was extracted from, a
+	// sibling of the folder it was extracted into; empty for folders.
+	Source string `json:"source,omitempty"`
+}
+
+// Failure is an archive that could not be extracted completely.
+type Failure struct {
+	Archive string `json:"archive"`
+	Error   string `json:"error"`
+}
+
+// Found reports whether the item had any archives.
+func (r Result) Found() bool {
+	return len(r.Archives) > 0 || len(r.Failed) > 0
+}
+
+// Item extracts the archives of a completed torrent. A folder has every
+// archive in it extracted next to the archive; a single archive file is
+// extracted into a folder named after it. The result is recorded in the
+// MarkerFile of the folder, and an item that already has one is not extracted
+// again.
+func Item(path string) (Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	root := path
+	var archives []string
+	if info.IsDir() {
+		archives, err = findArchives(path)
+		if err != nil {
+			return Result{}, err
+		}
+	} else if isFirstVolume(path) {
+		root = archiveFolder(path)
+		archives = []string{path}
+	}
+	if len(archives) == 0 {
+		return Result{}, nil
+	}
+
+	if previous, ok := readMarker(root); ok {
+		return previous, nil
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	if !info.IsDir() {
+		result.Source = filepath.Base(path)
+	}
+	for _, archive := range archives {
+		dest, name := root, filepath.Base(archive)
+		if info.IsDir() {
+			dest = filepath.Dir(archive)
+			name, _ = filepath.Rel(path, archive)
+		}
+
+		files, bytes, err := extractArchive(archive, dest)
+		result.Files += files
+		result.Bytes += bytes
+		if err != nil {
+			result.Failed = append(result.Failed, Failure{Archive: name, Error: err.Error()})
+			continue
+		}
+		result.Archives = append(result.Archives, name)
+	}
+
+	if err := writeMarker(root, result); err != nil {
+		return result, fmt.Errorf("writing extraction marker: %w", err)
+	}
+	return result, nil
+}
+
+// archiveFolder is the folder a single archive file is extracted into.
+func archiveFolder(path string) string {
+	root := strings.TrimSuffix(path, filepath.Ext(path))
+	return reRarPartSuffix.ReplaceAllString(root, "")
+}
+
+func isFirstVolume(path string) bool {
+	return reArchive.MatchString(path) && !reRarNextPart.MatchString(path)
+}
+
+// findArchives lists the first volumes of the archives under dir.
+func findArchives(dir string) ([]string, error) {
+	var archives []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && isFirstVolume(path) {
+			archives = append(archives, path)
+		}
+		return nil
+	})
+	return archives, err
+}
+
+func extractArchive(archive string, dest string) (int, int64, error) {
+	if strings.EqualFold(filepath.Ext(archive), ".zip") {
+		return extractZip(archive, dest)
+	}
+	return extractRar(archive, dest)
+}
+
+func extractZip(archive string, dest string) (int, int64, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Close()
+
+	var files int
+	var total int64
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return files, total, err
+		}
+		written, err := writeFile(dest, f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return files, total, err
+		}
+		if written >= 0 {
+			files++
+			total += written
+		}
+	}
+	return files, total, nil
+}
+
+func extractRar(archive string, dest string) (int, int64, error) {
+	r, err := rardecode.OpenReader(archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Close()
+
+	var files int
+	var total int64
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return files, total, nil
+		}
+		if err != nil {
+			return files, total, err
+		}
+		if !header.Mode().IsRegular() {
+			continue
+		}
+		written, err := writeFile(dest, header.Name, r)
+		if err != nil {
+			return files, total, err
+		}
+		if written >= 0 {
+			files++
+			total += written
+		}
+	}
+}
+
+// writeFile writes an archive entry under dest. Entries that would land
+// outside dest are rejected; existing files are kept and reported as -1.
+// A partially written file is removed.
+func writeFile(dest string, name string, content io.Reader) (int64, error) {
+	name = filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+	if !filepath.IsLocal(name) {
+		return 0, fmt.Errorf("unsafe path %q in archive", name)
+	}
+	target := filepath.Join(dest, name)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return 0, err
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(out, content)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return 0, fmt.Errorf("extracting %s: %w", name, err)
+	}
+	return written, nil
+}
+
+func readMarker(dir string) (Result, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, MarkerFile))
+	if err != nil {
+		return Result{}, false
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return Result{}, false
+	}
+	return result, true
+}
+
+func writeMarker(dir string, result Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, MarkerFile), data, 0o644)
+}
+
+// IsExtracted reports whether the item has been extracted: a folder by its
+// MarkerFile, a single archive file by the marker of the folder it was
+// extracted into.
+func IsExtracted(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, MarkerFile)); err == nil {
+		return true
+	}
+	if !isFirstVolume(path) {
+		return false
+	}
+	result, ok := readMarker(archiveFolder(path))
+	return ok && result.Source == filepath.Base(path)
+}
+
+// ExtractedFrom returns the archive a folder was extracted from when the
+// folder holds the contents of a single-archive item.
+func ExtractedFrom(dir string) (string, bool) {
+	result, ok := readMarker(dir)
+	if !ok || result.Source == "" {
+		return "", false
+	}
+	return result.Source, true
+}
@@ -0,0 +1,151 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyTestdata copies archives from testdata into a new folder in dir.
+func copyTestdata(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected %s to be extracted: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestItem_Folder(t *testing.T) {
+	item := filepath.Join(t.TempDir(), "Release")
+	copyTestdata(t, item, "release.rar", "extras.zip")
+	copyTestdata(t, filepath.Join(item, "CD1"), "album.part1.rar", "album.part2.rar", "album.part3.rar")
+
+	result, err := Item(item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Archives) != 3 || len(result.Failed) != 0 {
+		t.Fatalf("expected 3 extracted archives, got %#v", result)
+	}
+	if result.Files != 5 {
+		t.Errorf("expected 5 files, got %d", result.Files)
+	}
+
+	if got := readFile(t, filepath.Join(item, "movie.mkv")); !strings.HasPrefix(got, "not really a movie") {
+		t.Errorf("unexpected movie content %q", got)
+	}
+	readFile(t, filepath.Join(item, "subs", "movie.srt"))
+	readFile(t, filepath.Join(item, "extras", "poster.txt"))
+	if got := readFile(t, filepath.Join(item, "CD1", "album.flac")); len(got) != 768 {
+		t.Errorf("expected the multi-volume file to be joined, got %d bytes", len(got))
+	}
+
+	// Originals stay for seeding
+	readFile(t, filepath.Join(item, "release.rar"))
+	if !IsExtracted(item) {
+		t.Error("expected the item to be marked as extracted")
+	}
+
+	// A second run reports the recorded result without extracting again
+	os.Remove(filepath.Join(item, "movie.mkv"))
+	again, err := Item(item)
+	if err != nil || again.Files != result.Files {
+		t.Errorf("expected recorded result, got %#v, %v", again, err)
+	}
+	if _, err := os.Stat(filepath.Join(item, "movie.mkv")); !os.IsNotExist(err) {
+		t.Error("expected no second extraction")
+	}
+}
+
+func TestItem_SingleArchive(t *testing.T) {
+	dir := t.TempDir()
+	copyTestdata(t, dir, "album.part1.rar", "album.part2.rar", "album.part3.rar")
+
+	result, err := Item(filepath.Join(dir, "album.part1.rar"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Archives) != 1 || result.Archives[0] != "album.part1.rar" {
+		t.Errorf("unexpected result %#v", result)
+	}
+	readFile(t, filepath.Join(dir, "album", "album.flac"))
+	if !IsExtracted(filepath.Join(dir, "album.part1.rar")) {
+		t.Error("expected the archive to be marked as extracted")
+	}
+	if source, ok := ExtractedFrom(filepath.Join(dir, "album")); !ok || source != "album.part1.rar" {
+		t.Errorf("ExtractedFrom() = %q, %v", source, ok)
+	}
+}
+
+func TestItem_UnsafePaths(t *testing.T) {
+	root := t.TempDir()
+	item := filepath.Join(root, "a", "Release")
+	copyTestdata(t, item, "evil.rar", "slip.zip")
+
+	result, err := Item(item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Failed) != 2 || len(result.Archives) != 0 {
+		t.Fatalf("expected both archives to fail, got %#v", result)
+	}
+	for _, failure := range result.Failed {
+		if !strings.Contains(failure.Error, "unsafe path") {
+			t.Errorf("unexpected failure %#v", failure)
+		}
+	}
+	for _, escaped := range []string{filepath.Join(root, "a", "escape.txt"), filepath.Join(root, "escape.txt")} {
+		if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+			t.Errorf("archive escaped into %s", escaped)
+		}
+	}
+}
+
+func TestItem_NoArchives(t *testing.T) {
+	item := filepath.Join(t.TempDir(), "Movie")
+	os.MkdirAll(item, 0o755)
+	os.WriteFile(filepath.Join(item, "movie.mkv"), []byte("movie"), 0o644)
+
+	result, err := Item(item)
+	if err != nil || result.Found() {
+		t.Errorf("expected nothing to extract, got %#v, %v", result, err)
+	}
+	if IsExtracted(item) {
+		t.Error("expected no marker without archives")
+	}
+}
+
+func TestIsFirstVolume(t *testing.T) {
+	for path, want := range map[string]bool{
+		"a.rar":        true,
+		"a.part1.rar":  true,
+		"a.part01.rar": true,
+		"a.part2.rar":  false,
+		"a.part10.rar": false,
+		"a.r00":        false,
+		"A.ZIP":        true,
+		"movie.mkv":    false,
+	} {
+		if got := isFirstVolume(path); got != want {
+			t.Errorf("isFirstVolume(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
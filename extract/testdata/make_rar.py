@@ -0,0 +1,79 @@
+# Generates the stored (uncompressed) RAR5 archives in this folder, since no
+# RAR encoder is available in Go: python3 make_rar.py single release.rar,
+# python3 make_rar.py multi album, python3 make_rar.py evil evil.rar
+import zlib, struct, sys
+
+def vint(n):
+    out = bytearray()
+    while True:
+        b = n & 0x7f
+        n >>= 7
+        if n:
+            out.append(b | 0x80)
+        else:
+            out.append(b)
+            return bytes(out)
+
+def block(htype, hflags, body, data_size=None):
+    fields = vint(htype) + vint(hflags)
+    if data_size is not None:
+        fields += vint(data_size)
+    fields += body
+    hdr = vint(len(fields)) + fields
+    return struct.pack('<I', zlib.crc32(hdr) & 0xffffffff) + hdr
+
+SIG = b'Rar!\x1a\x07\x01\x00'
+
+def main_header(volume=False, volnum=None):
+    flags = 0
+    body = b''
+    if volume:
+        flags |= 1
+    if volnum is not None:
+        flags |= 2
+        body = vint(volnum)
+    return block(1, 0, vint(flags) + body)
+
+def file_header(name, data, total_size, crc, split_before=False, split_after=False, directory=False):
+    hflags = 0x0002
+    if split_after: hflags |= 0x0010
+    if split_before: hflags |= 0x0008
+    fflags = 0x0004 if crc is not None else 0
+    if directory: fflags |= 0x0001
+    body = vint(fflags) + vint(total_size) + vint(0o644 if not directory else 0o755)
+    if crc is not None:
+        body += struct.pack('<I', crc)
+    body += vint(0)  # store, version 0
+    body += vint(1)  # unix
+    nb = name.encode()
+    body += vint(len(nb)) + nb
+    return block(2, hflags, body, len(data)) + data
+
+def end_header(last=True):
+    return block(5, 0, vint(0 if last else 1))
+
+def single(path, files):
+    out = SIG + main_header()
+    for name, data in files:
+        out += file_header(name, data, len(data), zlib.crc32(data) & 0xffffffff)
+    out += end_header()
+    open(path, 'wb').write(out)
+
+def multi(base, name, data, parts):
+    size = (len(data) + parts - 1) // parts
+    chunks = [data[i*size:(i+1)*size] for i in range(parts)]
+    for i, chunk in enumerate(chunks):
+        out = SIG + main_header(volume=True, volnum=(i if i > 0 else None))
+        last = i == parts - 1
+        crc = zlib.crc32(data) & 0xffffffff if last else zlib.crc32(chunk) & 0xffffffff
+        out += file_header(name, chunk, len(data), crc, split_before=i > 0, split_after=not last)
+        out += end_header(last)
+        open('%s.part%d.rar' % (base, i + 1), 'wb').write(out)
+
+cmd = sys.argv[1]
+if cmd == 'single':
+    single(sys.argv[2], [("movie.mkv", b"not really a movie\n" * 4), ("subs/movie.srt", b"1\n00:00:01 --> 00:00:02\nhi\n")])
+elif cmd == 'multi':
+    multi(sys.argv[2], "album.flac", bytes(range(256)) * 3, 3)
+elif cmd == 'evil':
+    single(sys.argv[2], [("../escape.txt", b"nope\n")])
@@ -6,15 +6,25 @@ require (
 	github.com/minya/logger v0.0.0-20250510174529-7368e68ff9d7
 	github.com/minya/rutracker v0.0.0-20260305221146-1753e307f312
 	github.com/minya/telegram v0.0.0-20260125162800-ddf1ac8cb5c4
+	github.com/nwaples/rardecode/v2 v2.4.1
 	github.com/odwrtw/transmission v0.0.0-20221028215408-b11d7d55c759
+	github.com/prometheus/client_golang v1.24.1
+	github.com/prometheus/client_model v0.6.2
 )
 
 require github.com/rs/zerolog v1.34.0 // indirect
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/minya/goutils v0.0.0-20250705185653-54c0c51e5216 // indirect
-	golang.org/x/sys v0.33.0 // indirect
-	golang.org/x/text v0.33.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/common v0.70.1 // indirect
+	github.com/prometheus/procfs v0.21.1 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
 )
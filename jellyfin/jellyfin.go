@@ -0,0 +1,253 @@
+// Package jellyfin is a small client for the Jellyfin API, shared by the bot
+// and the Mini App.
+package jellyfin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/minya/tgtorrentbot/metrics"
+)
+
+// DefaultMediaPath is where the category folders are mounted in Jellyfin.
+const DefaultMediaPath = "/media"
+
+// Item represents a media item from the Jellyfin library.
+type Item struct {
+	Name     string
+	Category string
+	ID       string
+	// Path is the item's path as Jellyfin sees it.
+	Path string
+}
+
+// Client communicates with a Jellyfin server to retrieve library items.
+type Client struct {
+	url    string
+	apiKey string
+	// mediaPath is the folder that holds the category folders as Jellyfin sees it.
+	mediaPath string
+	client    *http.Client
+}
+
+// New creates a new Jellyfin API client. mediaPath is the folder that holds
+// the category folders as Jellyfin sees it, DefaultMediaPath when empty. If
+// url or apiKey is empty, GetItems will return an empty list.
+func New(url, apiKey, mediaPath string) *Client {
+	if mediaPath == "" {
+		mediaPath = DefaultMediaPath
+	}
+	return &Client{
+		url:       strings.TrimRight(url, "/"),
+		apiKey:    apiKey,
+		mediaPath: path.Clean(mediaPath),
+		client:    &http.Client{Timeout: 30 * time.Second, Transport: metrics.JellyfinTransport(operation)},
+	}
+}
+
+// operation names a request to Jellyfin in the metrics.
+func operation(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/Items"):
+		return "items"
+	case strings.HasSuffix(req.URL.Path, "/Library/Refresh"):
+		return "refresh_library"
+	case strings.HasSuffix(req.URL.Path, "/Library/Media/Updated"):
+		return "refresh_path"
+	default:
+		return "other"
+	}
+}
+
+// Configured reports whether the client has a server URL and an API key.
+func (c *Client) Configured() bool {
+	return c.url != "" && c.apiKey != ""
+}
+
+// jellyfinResponse represents the top-level Jellyfin /Items API response.
+type jellyfinResponse struct {
+	Items []jellyfinResponseItem `json:"Items"`
+}
+
+// jellyfinResponseItem represents a single item in the Jellyfin API response.
+type jellyfinResponseItem struct {
+	Name string `json:"Name"`
+	ID   string `json:"Id"`
+	Path string `json:"Path"`
+}
+
+// GetItems fetches all items from Jellyfin. Returns an empty list if Jellyfin
+// is not configured (empty URL or API key).
+func (c *Client) GetItems() ([]Item, error) {
+	if !c.Configured() {
+		return nil, nil
+	}
+
+	reqURL := fmt.Sprintf("%s/Items?Recursive=true&Fields=Path,MediaSources&IncludeItemTypes=Movie,Series,MusicAlbum,AudioBook,MusicVideo", c.url)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`MediaBrowser Token="%s"`, c.apiKey))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting jellyfin items: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jellyfin returned status %d", resp.StatusCode)
+	}
+
+	var jResp jellyfinResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jResp); err != nil {
+		return nil, fmt.Errorf("decoding jellyfin response: %w", err)
+	}
+
+	items := make([]Item, 0, len(jResp.Items))
+	for _, ri := range jResp.Items {
+		category := categoryFromPath(ri.Path, c.mediaPath)
+		name := folderNameFromPath(ri.Path, c.mediaPath)
+		if name == "" {
+			name = ri.Name
+		}
+		items = append(items, Item{
+			Name:     name,
+			Category: category,
+			ID:       ri.ID,
+			Path:     ri.Path,
+		})
+	}
+	return items, nil
+}
+
+// RefreshLibrary triggers a Jellyfin library scan so it picks up file changes.
+func (c *Client) RefreshLibrary() {
+	if !c.Configured() {
+		return
+	}
+
+	reqURL := fmt.Sprintf("%s/Library/Refresh", c.url)
+	req, err := http.NewRequest(http.MethodPost, reqURL, nil)
+	if err != nil {
+		logger.Error(err, "Failed to create Jellyfin refresh request")
+		return
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`MediaBrowser Token="%s"`, c.apiKey))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		logger.Error(err, "Failed to trigger Jellyfin library refresh")
+		return
+	}
+	logger.Debug("Jellyfin refresh response status: %v", resp)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		logger.Warn("Jellyfin library refresh returned status %d", resp.StatusCode)
+		return
+	}
+	logger.Info("Triggered Jellyfin library refresh")
+}
+
+// RefreshPath tells Jellyfin that a path in its library was created, so it
+// scans only that path instead of the whole library. The path is as Jellyfin
+// sees it, see ItemPath.
+func (c *Client) RefreshPath(path string) error {
+	if !c.Configured() {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"Updates": []map[string]string{{"Path": path, "UpdateType": "Created"}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url+"/Library/Media/Updated", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`MediaBrowser Token="%s"`, c.apiKey))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting jellyfin refresh: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("jellyfin returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ItemPath is the path of a download in a category as Jellyfin sees it,
+// e.g. /media/movies/Name.
+func (c *Client) ItemPath(category, name string) string {
+	return path.Join(c.mediaPath, category, name)
+}
+
+// FindItem looks up the library item of a download in a category: the item
+// at its ItemPath or inside it.
+func (c *Client) FindItem(category, name string) (Item, bool, error) {
+	items, err := c.GetItems()
+	if err != nil {
+		return Item{}, false, err
+	}
+	itemPath := c.ItemPath(category, name)
+	for _, item := range items {
+		p := path.Clean(filepath.ToSlash(item.Path))
+		if p == itemPath || strings.HasPrefix(p, itemPath+"/") {
+			return item, true, nil
+		}
+	}
+	return Item{}, false, nil
+}
+
+// WatchURL is the Jellyfin web page of an item on the server's public URL.
+func WatchURL(publicURL, itemID string) string {
+	return fmt.Sprintf("%s/web/#/details?id=%s", strings.TrimRight(publicURL, "/"), url.QueryEscape(itemID))
+}
+
+// relativeParts splits a Jellyfin item path into its components under
+// mediaPath; nil when the path is outside it.
+func relativeParts(p, mediaPath string) []string {
+	rel, ok := strings.CutPrefix(path.Clean(filepath.ToSlash(p)), strings.TrimSuffix(mediaPath, "/")+"/")
+	if !ok || rel == "" {
+		return nil
+	}
+	return strings.Split(rel, "/")
+}
+
+// folderNameFromPath extracts the top-level folder name under the category
+// from a Jellyfin item path. For example, given
+// /media/music/Collection/Album/track.mp3
+// it returns "Collection". Returns "" if the path doesn't have
+// enough segments.
+func folderNameFromPath(p, mediaPath string) string {
+	parts := relativeParts(p, mediaPath)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// categoryFromPath extracts the category from a Jellyfin item path.
+// Jellyfin paths look like {mediaPath}/{category}/ItemName/... so the category
+// is the first path component under mediaPath.
+func categoryFromPath(p, mediaPath string) string {
+	parts := relativeParts(p, mediaPath)
+	if len(parts) == 0 {
+		return "others"
+	}
+	return strings.ToLower(parts[0])
+}
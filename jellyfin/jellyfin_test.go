@@ -1,4 +1,4 @@
-package main
+package jellyfin
 
 import (
 	"encoding/json"
@@ -32,7 +32,7 @@ func TestGetItems(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	client := newJellyfinClient(srv.URL, "testkey")
+	client := New(srv.URL, "testkey", "")
 	items, err := client.GetItems()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -48,8 +48,8 @@ func TestGetItems(t *testing.T) {
 	if items[0].Category != "movies" {
 		t.Errorf("expected movies, got %s", items[0].Category)
 	}
-	if items[0].JellyfinID != "abc123" {
-		t.Errorf("expected abc123, got %s", items[0].JellyfinID)
+	if items[0].ID != "abc123" {
+		t.Errorf("expected abc123, got %s", items[0].ID)
 	}
 
 	// Check second item
@@ -65,7 +65,7 @@ func TestGetItems(t *testing.T) {
 
 func TestGetItemsNotConfigured(t *testing.T) {
 	// Empty URL
-	client := newJellyfinClient("", "somekey")
+	client := New("", "somekey", "")
 	items, err := client.GetItems()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -75,7 +75,7 @@ func TestGetItemsNotConfigured(t *testing.T) {
 	}
 
 	// Empty API key
-	client = newJellyfinClient("http://localhost:8096", "")
+	client = New("http://localhost:8096", "", "")
 	items, err = client.GetItems()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -91,7 +91,7 @@ func TestGetItemsServerError(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	client := newJellyfinClient(srv.URL, "testkey")
+	client := New(srv.URL, "testkey", "")
 	_, err := client.GetItems()
 	if err == nil {
 		t.Fatal("expected error for 500 response")
@@ -105,7 +105,7 @@ func TestGetItemsEmptyLibrary(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	client := newJellyfinClient(srv.URL, "testkey")
+	client := New(srv.URL, "testkey", "")
 	items, err := client.GetItems()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -129,10 +129,11 @@ func TestCategoryFromPath(t *testing.T) {
 		{"/some/other/path/file.mkv", "others"},
 		{"", "others"},
 		{"/media/", "others"},
+		{"/library/media/movies/The Matrix/file.mkv", "others"},
 	}
 
 	for _, tt := range tests {
-		got := categoryFromPath(tt.path)
+		got := categoryFromPath(tt.path, DefaultMediaPath)
 		if got != tt.expected {
 			t.Errorf("categoryFromPath(%q) = %q, want %q", tt.path, got, tt.expected)
 		}
@@ -149,7 +150,7 @@ func TestGetItemsTrailingSlashURL(t *testing.T) {
 	defer srv.Close()
 
 	// URL with trailing slash should still work
-	client := newJellyfinClient(srv.URL+"/", "testkey")
+	client := New(srv.URL+"/", "testkey", "")
 	items, err := client.GetItems()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -158,3 +159,75 @@ func TestGetItemsTrailingSlashURL(t *testing.T) {
 		t.Fatalf("expected 1 item, got %d", len(items))
 	}
 }
+
+func TestRefreshPath(t *testing.T) {
+	var got struct {
+		Updates []struct {
+			Path       string
+			UpdateType string
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/Library/Media/Updated" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := New(srv.URL, "testkey", "").RefreshPath("/media/movies/The Matrix"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Updates) != 1 || got.Updates[0].Path != "/media/movies/The Matrix" || got.Updates[0].UpdateType != "Created" {
+		t.Errorf("unexpected refresh body %+v", got)
+	}
+}
+
+func TestFindItem(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jellyfinResponse{Items: []jellyfinResponseItem{
+			{Name: "The Matrix", ID: "abc123", Path: "/media/movies/The Matrix/The Matrix.mkv"},
+			{Name: "Matrix OST", ID: "def456", Path: "/media/music/The Matrix/track.flac"},
+		}})
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "testkey", "")
+	item, ok, err := client.FindItem("music", "The Matrix")
+	if err != nil || !ok || item.ID != "def456" {
+		t.Errorf("expected the music item, got %+v %v %v", item, ok, err)
+	}
+	if _, ok, _ := client.FindItem("shows", "The Matrix"); ok {
+		t.Error("expected no match in another category")
+	}
+}
+
+func TestFindItemMediaPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jellyfinResponse{Items: []jellyfinResponseItem{
+			{Name: "The Matrix", ID: "abc123", Path: "/mnt/library/movies/The Matrix.mkv"},
+			{Name: "The Matrix Reloaded", ID: "def456", Path: "/mnt/library/movies/The Matrix Reloaded.mkv"},
+		}})
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "testkey", "/mnt/library/")
+	if got := client.ItemPath("movies", "The Matrix.mkv"); got != "/mnt/library/movies/The Matrix.mkv" {
+		t.Errorf("ItemPath() = %q", got)
+	}
+	item, ok, err := client.FindItem("movies", "The Matrix.mkv")
+	if err != nil || !ok || item.ID != "abc123" {
+		t.Errorf("expected the item under the media path, got %+v %v %v", item, ok, err)
+	}
+	items, _ := client.GetItems()
+	if len(items) != 2 || items[0].Category != "movies" || items[0].Name != "The Matrix.mkv" {
+		t.Errorf("expected categories under the media path, got %+v", items)
+	}
+}
+
+func TestWatchURL(t *testing.T) {
+	if got := WatchURL("https://media.example.org/", "abc123"); got != "https://media.example.org/web/#/details?id=abc123" {
+		t.Errorf("WatchURL() = %q", got)
+	}
+}
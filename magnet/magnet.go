@@ -0,0 +1,63 @@
+// Package magnet validates BitTorrent magnet URIs shared by the bot and the Mini App.
+package magnet
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxLength bounds the URIs we accept; real magnets with a few trackers are well below it.
+const maxLength = 8 << 10
+
+// reInfoHash matches a hex (v1), base32 (v1) or multihash (v2) info hash.
+var reInfoHash = regexp.MustCompile(`^(?i)urn:(btih:([0-9a-f]{40}|[a-z2-7]{32})|btmh:[0-9a-f]{68})$`)
+
+// Link is a parsed magnet URI.
+type Link struct {
+	URI         string
+	InfoHash    string
+	DisplayName string
+}
+
+// Name returns the display name if the magnet carries one, or the info hash otherwise.
+func (l Link) Name() string {
+	if l.DisplayName != "" {
+		return l.DisplayName
+	}
+	return l.InfoHash
+}
+
+// IsMagnet reports whether the text looks like a magnet URI.
+func IsMagnet(text string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(text)), "magnet:?")
+}
+
+// Parse validates a magnet URI and extracts its info hash and display name.
+func Parse(text string) (Link, error) {
+	uri := strings.TrimSpace(text)
+	if !IsMagnet(uri) {
+		return Link{}, fmt.Errorf("not a magnet link")
+	}
+	if len(uri) > maxLength {
+		return Link{}, fmt.Errorf("magnet link is too long")
+	}
+
+	params, err := url.ParseQuery(uri[len("magnet:?"):])
+	if err != nil {
+		return Link{}, fmt.Errorf("invalid magnet link: %w", err)
+	}
+
+	link := Link{URI: uri, DisplayName: params.Get("dn")}
+	for _, xt := range params["xt"] {
+		if found := reInfoHash.FindStringSubmatch(xt); found != nil {
+			link.InfoHash = strings.ToLower(xt[strings.LastIndex(xt, ":")+1:])
+			break
+		}
+	}
+	if link.InfoHash == "" {
+		return Link{}, fmt.Errorf("magnet link has no BitTorrent info hash")
+	}
+	return link, nil
+}
@@ -0,0 +1,53 @@
+package magnet
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Some+Movie+2021&tr=udp%3A%2F%2Ftracker.example.org%3A80"
+	link, err := Parse("  " + uri + "\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.URI != uri {
+		t.Errorf("URI = %q, want trimmed input", link.URI)
+	}
+	if link.InfoHash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+		t.Errorf("InfoHash = %q", link.InfoHash)
+	}
+	if link.Name() != "Some Movie 2021" {
+		t.Errorf("Name() = %q", link.Name())
+	}
+}
+
+func TestParse_NameFallsBackToInfoHash(t *testing.T) {
+	link, err := Parse("magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.Name() != link.InfoHash {
+		t.Errorf("Name() = %q, want info hash", link.Name())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, text := range []string{
+		"dune 2021",
+		"https://example.org/file.torrent",
+		"magnet:?dn=NoHash",
+		"magnet:?xt=urn:btih:nothex",
+		"magnet:?xt=urn:sha1:c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestIsMagnet(t *testing.T) {
+	if !IsMagnet(" MAGNET:?xt=urn:btih:abc") {
+		t.Error("expected magnet prefix to be case-insensitive")
+	}
+	if IsMagnet("magnet link please") {
+		t.Error("expected plain text not to be a magnet")
+	}
+}
@@ -0,0 +1,57 @@
+// Package metrics serves the Prometheus metrics of the bot and the Mini App,
+// and holds the metrics of the services both of them call. Metrics are
+// registered with the default registry of client_golang, which also reports
+// the Go runtime and the process.
+package metrics
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/minya/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of latency histograms; they
+// reach the 30s timeout of the Rutracker and Jellyfin clients.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Serve serves /metrics in the background on the configured address, or on
+// defaultAddress when none is configured. Only the default may be taken by
+// another program; a configured address that can't be served is fatal.
+func Serve(address string, defaultAddress string) {
+	if err := listen(cmp.Or(address, defaultAddress)); err != nil {
+		if address != "" {
+			logger.Fatal(err, "Failed to serve metrics")
+		}
+		logger.Error(err, "Failed to serve metrics")
+	}
+}
+
+// listen serves /metrics on the address in the background. It fails when the
+// address can't be listened on.
+func listen(address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("serving metrics: %w", err)
+	}
+	logger.Info("Serving metrics on http://%s/metrics", ln.Addr())
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Metrics server failed")
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// observations is the number of observations in the series of the label.
+func observations(t *testing.T, h *prometheus.HistogramVec, label string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := h.WithLabelValues(label).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestServe_AddressInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if err := listen(ln.Addr().String()); err == nil {
+		t.Error("expected an error for an address in use")
+	}
+}
+
+func TestTransmissionClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Transmission-Session-Id") == "" {
+			w.Header().Set("X-Transmission-Session-Id", "session")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if strings.Contains(r.URL.Path, "broken") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	client := TransmissionClient()
+
+	post := func(path, session string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(`{"method": "session-stats"}`))
+		if session != "" {
+			req.Header.Set("X-Transmission-Session-Id", session)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	before := observations(t, transmissionDuration, "session-stats")
+	errorsBefore := testutil.ToFloat64(transmissionErrors.WithLabelValues("session-stats"))
+	post("/", "")
+	post("/", "session")
+	post("/broken", "session")
+
+	if got := observations(t, transmissionDuration, "session-stats") - before; got != 2 {
+		t.Errorf("expected 2 calls recorded without the session handshake, got %d", got)
+	}
+	if got := testutil.ToFloat64(transmissionErrors.WithLabelValues("session-stats")) - errorsBefore; got != 1 {
+		t.Errorf("expected 1 failed call, got %v", got)
+	}
+}
+
+func TestObserveRutracker(t *testing.T) {
+	before := testutil.ToFloat64(rutrackerFailures.WithLabelValues(RutrackerSearch))
+	ObserveRutracker(RutrackerSearch, time.Now(), nil)
+	ObserveRutracker(RutrackerSearch, time.Now(), errors.New("timeout"))
+	if got := testutil.ToFloat64(rutrackerFailures.WithLabelValues(RutrackerSearch)) - before; got != 1 {
+		t.Errorf("expected 1 failure, got %v", got)
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Rutracker operations
+const (
+	RutrackerLogin    = "login"
+	RutrackerSearch   = "search"
+	RutrackerDownload = "download"
+)
+
+// Metrics of the services shared by the bot and the Mini App
+var (
+	rutrackerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tgt_rutracker_request_duration_seconds",
+		Help:    "Duration of Rutracker requests, by operation.",
+		Buckets: DefaultBuckets,
+	}, []string{"operation"})
+	rutrackerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_rutracker_request_failures_total",
+		Help: "Failed Rutracker requests, by operation.",
+	}, []string{"operation"})
+
+	transmissionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tgt_transmission_rpc_duration_seconds",
+		Help:    "Duration of Transmission RPC calls, by method.",
+		Buckets: DefaultBuckets,
+	}, []string{"method"})
+	transmissionErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_transmission_rpc_errors_total",
+		Help: "Failed Transmission RPC calls, by method.",
+	}, []string{"method"})
+
+	jellyfinDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tgt_jellyfin_request_duration_seconds",
+		Help:    "Duration of Jellyfin API requests, by operation.",
+		Buckets: DefaultBuckets,
+	}, []string{"operation"})
+	jellyfinErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgt_jellyfin_request_errors_total",
+		Help: "Failed Jellyfin API requests, by operation.",
+	}, []string{"operation"})
+)
+
+// ObserveRutracker records a Rutracker operation that began at start. The
+// Rutracker client has no hook for its HTTP client, so callers time it.
+func ObserveRutracker(operation string, start time.Time, err error) {
+	rutrackerDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		rutrackerFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// transport times the requests of an HTTP client. A response with an error
+// status counts as failed unless ignore accepts it.
+type transport struct {
+	base     http.RoundTripper
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	// label names the request; it may read the body if it restores it.
+	label  func(*http.Request) string
+	ignore func(*http.Response) bool
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	label := t.label(req)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && t.ignore != nil && t.ignore(resp) {
+		return resp, nil
+	}
+	t.duration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+	if err != nil || resp.StatusCode >= 400 {
+		t.errors.WithLabelValues(label).Inc()
+	}
+	return resp, err
+}
+
+// TransmissionClient is an HTTP client for the Transmission RPC client that
+// records each call by its RPC method.
+func TransmissionClient() *http.Client {
+	return &http.Client{Transport: &transport{
+		base:     http.DefaultTransport,
+		duration: transmissionDuration,
+		errors:   transmissionErrors,
+		label:    transmissionMethod,
+		// Transmission answers 409 to hand out a session ID, and the call is
+		// retried with it
+		ignore: func(resp *http.Response) bool { return resp.StatusCode == http.StatusConflict },
+	}}
+}
+
+func transmissionMethod(req *http.Request) string {
+	if req.Body == nil {
+		return "unknown"
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	var rpc struct {
+		Method string `json:"method"`
+	}
+	if err != nil || json.Unmarshal(body, &rpc) != nil || rpc.Method == "" {
+		return "unknown"
+	}
+	return rpc.Method
+}
+
+// JellyfinTransport records the requests of a Jellyfin client; operation
+// names each request.
+func JellyfinTransport(operation func(*http.Request) string) http.RoundTripper {
+	return &transport{
+		base:     http.DefaultTransport,
+		duration: jellyfinDuration,
+		errors:   jellyfinErrors,
+		label:    operation,
+	}
+}
@@ -0,0 +1,177 @@
+// Package preferences stores per-user bot preferences in a JSON file.
+package preferences
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is a kind of message the bot sends on its own.
+type Event string
+
+const (
+	EventCompleted Event = "completed"
+	EventAdded     Event = "added"
+	EventStalled   Event = "stalled"
+	EventDigest    Event = "digest"
+	// EventHookFailed and EventSeeding can't be turned off, they only follow
+	// the quiet hours and silent flag.
+	EventHookFailed Event = "hook-failed"
+	EventSeeding    Event = "seeding"
+)
+
+// QuietHours is a daily local time range, in whole hours, during which
+// messages are delivered without sound. From may be greater than To to span midnight.
+type QuietHours struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Contains reports whether the local hour of t is within the quiet hours.
+func (q QuietHours) Contains(t time.Time) bool {
+	hour := t.Hour()
+	if q.From <= q.To {
+		return hour >= q.From && hour < q.To
+	}
+	return hour >= q.From || hour < q.To
+}
+
+func (q QuietHours) String() string {
+	return fmt.Sprintf("%02d:00–%02d:00", q.From, q.To)
+}
+
+// QuietHoursPresets are the quiet hours offered in the settings menu.
+var QuietHoursPresets = []QuietHours{{22, 8}, {23, 7}, {0, 9}}
+
+// Preferences are the choices of a single user.
+type Preferences struct {
+	Completed bool `json:"completed"`
+	Added     bool `json:"added"`
+	Stalled   bool `json:"stalled"`
+	// Digest enables the daily digest.
+	Digest bool `json:"digest"`
+	// QuietHours is nil when messages are never muted by time of day.
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	// Silent delivers every message without sound.
+	Silent bool `json:"silent"`
+}
+
+// Wants reports whether the user wants messages about the event.
+func (p Preferences) Wants(event Event) bool {
+	switch event {
+	case EventCompleted:
+		return p.Completed
+	case EventAdded:
+		return p.Added
+	case EventStalled:
+		return p.Stalled
+	case EventDigest:
+		return p.Digest
+	default:
+		return true
+	}
+}
+
+// SilentAt reports whether a message sent at t should be delivered without sound.
+func (p Preferences) SilentAt(t time.Time) bool {
+	return p.Silent || (p.QuietHours != nil && p.QuietHours.Contains(t))
+}
+
+// Store keeps the preferences of all users. An empty path keeps them in memory only.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	users map[int64]Preferences
+}
+
+// Load reads the preferences file; a missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	store := &Store{path: path, users: map[int64]Preferences{}}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("reading preferences: %w", err)
+	}
+	var raw map[int64]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return store, fmt.Errorf("parsing preferences: %w", err)
+	}
+	// Fields missing from older files keep their defaults
+	for userID, entry := range raw {
+		prefs := Default()
+		if err := json.Unmarshal(entry, &prefs); err != nil {
+			return &Store{path: path, users: map[int64]Preferences{}}, fmt.Errorf("parsing preferences of %d: %w", userID, err)
+		}
+		store.users[userID] = prefs
+	}
+	return store, nil
+}
+
+// Get returns the preferences of a user, or the defaults if they never changed any.
+func (s *Store) Get(userID int64) Preferences {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prefs, ok := s.users[userID]; ok {
+		return prefs
+	}
+	return Default()
+}
+
+// Delivery reports whether a message about the event should be sent to the
+// user at now and whether it should be silent. A nil store sends everything loudly.
+func (s *Store) Delivery(userID int64, event Event, now time.Time) (send bool, silent bool) {
+	if s == nil {
+		return true, false
+	}
+	prefs := s.Get(userID)
+	return prefs.Wants(event), prefs.SilentAt(now)
+}
+
+// Update changes the preferences of a user and saves the store.
+func (s *Store) Update(userID int64, change func(*Preferences)) (Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs, ok := s.users[userID]
+	if !ok {
+		prefs = Default()
+	}
+	change(&prefs)
+	s.users[userID] = prefs
+	return prefs, s.save()
+}
+
+// Default returns the preferences of a user who never changed any.
+func Default() Preferences {
+	return Preferences{Completed: true, Added: true, Stalled: true}
+}
+
+// save writes the store atomically; the caller holds the lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,109 @@
+package preferences
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreUpdateAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefs", "preferences.json")
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.Get(42) != Default() {
+		t.Errorf("expected defaults for an unknown user")
+	}
+
+	if _, err := store.Update(42, func(p *Preferences) { p.Digest = true }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reloaded.Get(42).Digest {
+		t.Error("expected digest preference to be persisted")
+	}
+	if reloaded.Get(7).Digest {
+		t.Error("expected other users to keep defaults")
+	}
+}
+
+func TestStoreInMemory(t *testing.T) {
+	store, _ := Load("")
+	if _, err := store.Update(1, func(p *Preferences) { p.Digest = true }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !store.Get(1).Digest {
+		t.Error("expected in-memory update to stick")
+	}
+}
+
+func TestLoadKeepsDefaultsForMissingFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "preferences.json")
+	os.WriteFile(path, []byte(`{"42":{"digest":true}}`), 0o644)
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prefs := store.Get(42)
+	if !prefs.Digest || !prefs.Completed || !prefs.Added || !prefs.Stalled {
+		t.Errorf("expected defaults for missing fields, got %#v", prefs)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	night := QuietHours{From: 22, To: 8}
+	day := QuietHours{From: 13, To: 15}
+	at := func(hour int) time.Time { return time.Date(2026, 1, 1, hour, 30, 0, 0, time.UTC) }
+
+	for hour, want := range map[int]bool{23: true, 3: true, 8: false, 12: false, 22: true} {
+		if got := night.Contains(at(hour)); got != want {
+			t.Errorf("night.Contains(%d) = %v, want %v", hour, got, want)
+		}
+	}
+	if !day.Contains(at(14)) || day.Contains(at(15)) {
+		t.Error("unexpected daytime range result")
+	}
+
+	prefs := Default()
+	prefs.QuietHours = &night
+	if !prefs.SilentAt(at(2)) || prefs.SilentAt(at(12)) {
+		t.Error("expected messages to be silent only during quiet hours")
+	}
+	prefs.Silent = true
+	if !prefs.SilentAt(at(12)) {
+		t.Error("expected silent flag to mute every message")
+	}
+}
+
+func TestWants(t *testing.T) {
+	prefs := Default()
+	if !prefs.Wants(EventCompleted) || prefs.Wants(EventDigest) {
+		t.Errorf("unexpected defaults %#v", prefs)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	var nilStore *Store
+	if send, silent := nilStore.Delivery(1, EventStalled, time.Now()); !send || silent {
+		t.Errorf("nil store should send loudly, got send=%v silent=%v", send, silent)
+	}
+
+	store, _ := Load(filepath.Join(t.TempDir(), "preferences.json"))
+	store.Update(1, func(p *Preferences) {
+		p.Stalled = false
+		p.Silent = true
+	})
+	if send, _ := store.Delivery(1, EventStalled, time.Now()); send {
+		t.Error("expected stalled alerts to be skipped")
+	}
+	if send, silent := store.Delivery(1, EventCompleted, time.Now()); !send || !silent {
+		t.Errorf("expected a silent completion, got send=%v silent=%v", send, silent)
+	}
+}
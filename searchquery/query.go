@@ -0,0 +1,244 @@
+// Package searchquery parses the search filter language shared by the bot and
+// the Mini App, e.g. "dune 2021 size<20GB seeders>=5 sort:size -cam".
+//
+// Supported terms:
+//
+//	size<20GB, size>=700MB   size constraint (B, KB, MB, GB, TB)
+//	seeders>=5, seeds<100    seeders constraint
+//	-cam                     exclude titles with the word "cam" (case-insensitive)
+//	sort:size[:asc|:desc]    sort by seeders (default), size or title
+//
+// Everything else is passed to Rutracker as the search text.
+package searchquery
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/minya/rutracker"
+)
+
+// SortField is the field search results are sorted by.
+type SortField string
+
+const (
+	SortBySeeders SortField = "seeders"
+	SortBySize    SortField = "size"
+	SortByTitle   SortField = "title"
+)
+
+// Comparison is a single numeric constraint such as "size<20GB".
+type Comparison struct {
+	Op    string
+	Value float64
+}
+
+// Matches reports whether v satisfies the comparison.
+func (c Comparison) Matches(v float64) bool {
+	switch c.Op {
+	case "<":
+		return v < c.Value
+	case "<=":
+		return v <= c.Value
+	case ">":
+		return v > c.Value
+	case ">=":
+		return v >= c.Value
+	case "=":
+		return v == c.Value
+	}
+	return false
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Text     string
+	Size     []Comparison // in bytes
+	Seeders  []Comparison
+	Exclude  []string // lowercase words
+	SortBy   SortField
+	SortDesc bool
+}
+
+var (
+	reComparison = regexp.MustCompile(`^(?i)(size|seeders|seeds)(<=|>=|<|>|=)(.+)$`)
+	reSizeValue  = regexp.MustCompile(`^(?i)(\d+(?:[.,]\d+)?)\s*(b|kb|mb|gb|tb)$`)
+)
+
+var sizeUnits = map[string]float64{
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+}
+
+// Parse parses a raw query. All invalid terms are reported in a single error.
+func Parse(raw string) (Query, error) {
+	q := Query{SortBy: SortBySeeders, SortDesc: true}
+	var words []string
+	var problems []string
+
+	for _, term := range strings.Fields(raw) {
+		switch {
+		case reComparison.MatchString(term):
+			found := reComparison.FindStringSubmatch(term)
+			field, op, value := strings.ToLower(found[1]), found[2], found[3]
+			if field == "size" {
+				bytes, err := parseSize(value)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", term, err))
+					continue
+				}
+				q.Size = append(q.Size, Comparison{Op: op, Value: bytes})
+			} else {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					problems = append(problems, fmt.Sprintf("%s: seeders must be a non-negative number", term))
+					continue
+				}
+				q.Seeders = append(q.Seeders, Comparison{Op: op, Value: float64(n)})
+			}
+
+		case strings.HasPrefix(strings.ToLower(term), "sort:"):
+			if err := q.parseSort(term[len("sort:"):]); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", term, err))
+			}
+
+		case len(term) > 1 && term[0] == '-':
+			q.Exclude = append(q.Exclude, strings.ToLower(term[1:]))
+
+		default:
+			words = append(words, term)
+		}
+	}
+
+	q.Text = strings.Join(words, " ")
+	if q.Text == "" {
+		problems = append(problems, "search text is required")
+	}
+	if len(problems) > 0 {
+		return q, fmt.Errorf("invalid search query: %s", strings.Join(problems, "; "))
+	}
+	return q, nil
+}
+
+func (q *Query) parseSort(spec string) error {
+	field, direction, hasDirection := strings.Cut(strings.ToLower(spec), ":")
+	switch SortField(field) {
+	case SortBySeeders, SortBySize:
+		q.SortBy, q.SortDesc = SortField(field), true
+	case SortByTitle:
+		q.SortBy, q.SortDesc = SortByTitle, false
+	default:
+		return fmt.Errorf("unknown sort field %q (use seeders, size or title)", field)
+	}
+	if !hasDirection {
+		return nil
+	}
+	switch direction {
+	case "asc":
+		q.SortDesc = false
+	case "desc":
+		q.SortDesc = true
+	default:
+		return fmt.Errorf("unknown sort direction %q (use asc or desc)", direction)
+	}
+	return nil
+}
+
+func parseSize(s string) (float64, error) {
+	found := reSizeValue.FindStringSubmatch(s)
+	if len(found) != 3 {
+		return 0, fmt.Errorf("size must look like 700MB or 1.5GB")
+	}
+	n, err := strconv.ParseFloat(strings.Replace(found[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * sizeUnits[strings.ToLower(found[2])], nil
+}
+
+// ItemSizeBytes converts a Rutracker item size to bytes. Unknown units yield 0.
+func ItemSizeBytes(size rutracker.ItemSize) float64 {
+	unit := strings.ToLower(strings.TrimSpace(size.Unit))
+	return size.Size * sizeUnits[unit]
+}
+
+// Apply filters and sorts search results according to the query.
+func (q Query) Apply(items []rutracker.RutrackerSearchItem) []rutracker.RutrackerSearchItem {
+	result := make([]rutracker.RutrackerSearchItem, 0, len(items))
+	for _, item := range items {
+		if q.matches(item) {
+			result = append(result, item)
+		}
+	}
+
+	less := func(a, b rutracker.RutrackerSearchItem) bool {
+		switch q.SortBy {
+		case SortBySize:
+			return ItemSizeBytes(a.Size) < ItemSizeBytes(b.Size)
+		case SortByTitle:
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		default:
+			return a.Seeders < b.Seeders
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if q.SortDesc {
+			return less(result[j], result[i])
+		}
+		return less(result[i], result[j])
+	})
+	return result
+}
+
+func (q Query) matches(item rutracker.RutrackerSearchItem) bool {
+	size := ItemSizeBytes(item.Size)
+	for _, c := range q.Size {
+		if !c.Matches(size) {
+			return false
+		}
+	}
+	for _, c := range q.Seeders {
+		if !c.Matches(float64(item.Seeders)) {
+			return false
+		}
+	}
+	title := strings.ToLower(item.Title)
+	for _, word := range q.Exclude {
+		if containsWord(title, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsWord reports whether word occurs in s with no letter or digit right
+// before or after it, so "cam" matches "[CAM]" but not "Cameron".
+func containsWord(s, word string) bool {
+	for i := 0; i < len(s); {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		i = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,115 @@
+package searchquery
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minya/rutracker"
+)
+
+func TestParse_FullQuery(t *testing.T) {
+	q, err := Parse("dune 2021 size<20GB seeders>=5 sort:size -cam")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Text != "dune 2021" {
+		t.Errorf("Text = %q, want %q", q.Text, "dune 2021")
+	}
+	if len(q.Size) != 1 || q.Size[0].Op != "<" || q.Size[0].Value != 20*(1<<30) {
+		t.Errorf("unexpected size constraints: %#v", q.Size)
+	}
+	if len(q.Seeders) != 1 || q.Seeders[0].Op != ">=" || q.Seeders[0].Value != 5 {
+		t.Errorf("unexpected seeders constraints: %#v", q.Seeders)
+	}
+	if len(q.Exclude) != 1 || q.Exclude[0] != "cam" {
+		t.Errorf("unexpected exclusions: %#v", q.Exclude)
+	}
+	if q.SortBy != SortBySize || !q.SortDesc {
+		t.Errorf("unexpected sort: %s desc=%v", q.SortBy, q.SortDesc)
+	}
+}
+
+func TestParse_Defaults(t *testing.T) {
+	q, err := Parse("  the matrix  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Text != "the matrix" {
+		t.Errorf("Text = %q, want %q", q.Text, "the matrix")
+	}
+	if q.SortBy != SortBySeeders || !q.SortDesc {
+		t.Errorf("expected default sort by seeders descending, got %s desc=%v", q.SortBy, q.SortDesc)
+	}
+}
+
+func TestParse_SortDirection(t *testing.T) {
+	q, err := Parse("matrix sort:size:asc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.SortBy != SortBySize || q.SortDesc {
+		t.Errorf("expected size ascending, got %s desc=%v", q.SortBy, q.SortDesc)
+	}
+}
+
+func TestParse_ReportsAllProblems(t *testing.T) {
+	_, err := Parse("size<huge seeders>=many sort:date")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"size<huge", "seeders>=many", "sort:date", "search text is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestApply_FiltersAndSorts(t *testing.T) {
+	items := []rutracker.RutrackerSearchItem{
+		{Title: "Dune 2021 REMUX", Seeders: 50, Size: rutracker.ItemSize{Size: 70, Unit: "GB"}},
+		{Title: "Dune 2021 [CAM]", Seeders: 500, Size: rutracker.ItemSize{Size: 1.4, Unit: "GB"}},
+		{Title: "Dune 2021 1080p", Seeders: 120, Size: rutracker.ItemSize{Size: 12, Unit: "GB"}},
+		{Title: "Dune 2021 720p", Seeders: 3, Size: rutracker.ItemSize{Size: 4, Unit: "GB"}},
+		{Title: "Dune 2021 x265", Seeders: 40, Size: rutracker.ItemSize{Size: 900, Unit: "MB"}},
+	}
+
+	q, err := Parse("dune size<20GB seeders>=5 sort:size -cam")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := q.Apply(items)
+
+	if len(result) != 2 {
+		t.Fatalf("expected 2 results, got %d: %v", len(result), result)
+	}
+	if result[0].Title != "Dune 2021 1080p" || result[1].Title != "Dune 2021 x265" {
+		t.Errorf("unexpected order: %q, %q", result[0].Title, result[1].Title)
+	}
+}
+
+func TestApply_DefaultSortBySeeders(t *testing.T) {
+	items := []rutracker.RutrackerSearchItem{
+		{Title: "a", Seeders: 1},
+		{Title: "b", Seeders: 10},
+		{Title: "c", Seeders: 5},
+	}
+	q, _ := Parse("query")
+	result := q.Apply(items)
+	if result[0].Title != "b" || result[1].Title != "c" || result[2].Title != "a" {
+		t.Errorf("unexpected order: %v", result)
+	}
+}
+
+func TestApply_ExcludesWholeWords(t *testing.T) {
+	items := []rutracker.RutrackerSearchItem{
+		{Title: "Titanic (Cameron) 1997 BDRip"},
+		{Title: "Camera Buff 1979"},
+		{Title: "Titanic 1997 TS/CAM"},
+		{Title: "Titanic 1997 CAM"},
+	}
+	q, _ := Parse("titanic -cam")
+	result := q.Apply(items)
+	if len(result) != 2 || result[0].Title != "Titanic (Cameron) 1997 BDRip" || result[1].Title != "Camera Buff 1979" {
+		t.Errorf("expected only the titles with the word cam to be excluded, got %v", result)
+	}
+}
@@ -0,0 +1,135 @@
+// Package seeding decides when completed torrents stop seeding, following
+// the policy configured for their category.
+package seeding
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// AnyCategory is the category key of the policy for categories without their own.
+const AnyCategory = "*"
+
+// Actions taken once a policy's limit is reached
+const (
+	// ActionStop pauses the torrent; it stays in Transmission with its data.
+	ActionStop = "stop"
+	// ActionRemove removes the torrent from Transmission and keeps its data.
+	ActionRemove = "remove"
+)
+
+// Policy is the seeding rule of a category. A torrent that reaches either
+// limit gets the action; a policy without limits applies it right on completion.
+type Policy struct {
+	// Ratio is the upload ratio to seed to; 0 means no ratio limit.
+	Ratio float64 `json:"ratio,omitempty"`
+	// Days is how long to seed after completion; 0 means no time limit.
+	Days int `json:"days,omitempty"`
+	// Action is ActionStop (the default) or ActionRemove.
+	Action string `json:"action,omitempty"`
+	// Forever keeps seeding, overriding the policy for any category.
+	Forever bool `json:"forever,omitempty"`
+}
+
+// Config maps a category, or AnyCategory, to its policy.
+type Config map[string]Policy
+
+// Torrent is what the policies look at.
+type Torrent struct {
+	Category string
+	// Ratio is Transmission's upload ratio, negative when not applicable.
+	Ratio float64
+	// DoneAt is when the download completed.
+	DoneAt time.Time
+}
+
+// Decision is what to do with a torrent and why.
+type Decision struct {
+	Action string
+	Reason string
+}
+
+// Engine applies the configured policies.
+type Engine struct {
+	policies Config
+}
+
+// New validates the configuration and reports every invalid policy at once.
+func New(config Config) (*Engine, error) {
+	var problems []string
+	for category, policy := range config {
+		if err := policy.validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", category, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid seeding policies: %v", problems)
+	}
+	return &Engine{policies: config}, nil
+}
+
+func (p Policy) validate() error {
+	switch {
+	case p.Forever && (p.Ratio != 0 || p.Days != 0 || p.Action != ""):
+		return fmt.Errorf("forever can't be combined with limits or an action")
+	case p.Forever:
+		return nil
+	case p.Ratio < 0 || math.IsNaN(p.Ratio) || math.IsInf(p.Ratio, 0):
+		return fmt.Errorf("invalid ratio %v", p.Ratio)
+	case p.Days < 0:
+		return fmt.Errorf("invalid days %d", p.Days)
+	case p.Action != "" && p.Action != ActionStop && p.Action != ActionRemove:
+		return fmt.Errorf("unknown action %q", p.Action)
+	case p.Ratio == 0 && p.Days == 0 && p.Action == "":
+		return fmt.Errorf("set ratio, days, action or forever")
+	}
+	return nil
+}
+
+// Enabled reports whether any policy is configured.
+func (e *Engine) Enabled() bool {
+	return e != nil && len(e.policies) > 0
+}
+
+// Policy returns the policy of the category, falling back to AnyCategory.
+func (e *Engine) Policy(category string) (Policy, bool) {
+	if e == nil {
+		return Policy{}, false
+	}
+	if policy, ok := e.policies[category]; ok {
+		return policy, true
+	}
+	policy, ok := e.policies[AnyCategory]
+	return policy, ok
+}
+
+// Decide reports whether the torrent's policy calls for an action now.
+func (e *Engine) Decide(torrent Torrent, now time.Time) (Decision, bool) {
+	policy, ok := e.Policy(torrent.Category)
+	if !ok || policy.Forever {
+		return Decision{}, false
+	}
+	action := policy.Action
+	if action == "" {
+		action = ActionStop
+	}
+
+	if policy.Ratio == 0 && policy.Days == 0 {
+		return Decision{Action: action, Reason: "completed"}, true
+	}
+	if policy.Ratio > 0 && torrent.Ratio >= policy.Ratio {
+		return Decision{Action: action, Reason: fmt.Sprintf("reached ratio %.2f", torrent.Ratio)}, true
+	}
+	if policy.Days > 0 && !torrent.DoneAt.IsZero() && now.Sub(torrent.DoneAt) >= time.Duration(policy.Days)*24*time.Hour {
+		return Decision{Action: action, Reason: fmt.Sprintf("seeded for %s", formatDays(policy.Days))}, true
+	}
+	return Decision{}, false
+}
+
+func formatDays(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
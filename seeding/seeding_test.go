@@ -0,0 +1,82 @@
+package seeding
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{
+		"movies": {Ratio: -1},
+		"music":  {Days: 3, Action: "delete"},
+		"books":  {},
+		"series": {Forever: true, Ratio: 2},
+		"others": {Ratio: 2},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"movies", "music", "books", "series"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to be reported in %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "others") {
+		t.Errorf("did not expect the valid policy to be reported in %v", err)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	engine, err := New(Config{
+		"movies":    {Ratio: 2, Days: 14},
+		"music":     {Forever: true},
+		"books":     {Action: ActionRemove},
+		AnyCategory: {Days: 30, Action: ActionRemove},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		torrent Torrent
+		ok      bool
+		want    Decision
+	}{
+		{"below limits", Torrent{Category: "movies", Ratio: 1.5, DoneAt: now.AddDate(0, 0, -3)}, false, Decision{}},
+		{"ratio reached", Torrent{Category: "movies", Ratio: 2.1, DoneAt: now.AddDate(0, 0, -3)}, true, Decision{ActionStop, "reached ratio 2.10"}},
+		{"days reached", Torrent{Category: "movies", Ratio: 0.3, DoneAt: now.AddDate(0, 0, -14)}, true, Decision{ActionStop, "seeded for 14 days"}},
+		{"ratio not applicable", Torrent{Category: "movies", Ratio: -1, DoneAt: now}, false, Decision{}},
+		{"forever", Torrent{Category: "music", Ratio: 50, DoneAt: now.AddDate(-1, 0, 0)}, false, Decision{}},
+		{"on completion", Torrent{Category: "books", DoneAt: now}, true, Decision{ActionRemove, "completed"}},
+		{"any category", Torrent{Category: "others", DoneAt: now.AddDate(0, 0, -31)}, true, Decision{ActionRemove, "seeded for 30 days"}},
+		{"unknown completion date", Torrent{Category: "others"}, false, Decision{}},
+	}
+	for _, tt := range tests {
+		got, ok := engine.Decide(tt.torrent, now)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: got %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecide_NoPolicies(t *testing.T) {
+	var engine *Engine
+	if engine.Enabled() {
+		t.Error("expected a nil engine to be disabled")
+	}
+	if _, ok := engine.Decide(Torrent{Category: "movies", Ratio: 10}, now); ok {
+		t.Error("expected no decision without policies")
+	}
+
+	engine, err := New(Config{"movies": {Ratio: 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := engine.Decide(Torrent{Category: "music", Ratio: 10}, now); ok {
+		t.Error("expected no decision for a category without a policy")
+	}
+}
@@ -0,0 +1,177 @@
+// Package torrentlink recognises pasted Rutracker links and .torrent URLs on
+// trusted hosts, shared by the bot and the Mini App.
+package torrentlink
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxTorrentSize bounds the .torrent files fetched from trusted hosts.
+const MaxTorrentSize = 10 << 20
+
+// maxRedirects bounds the redirects followed when fetching a .torrent file.
+const maxRedirects = 5
+
+var (
+	rutrackerHosts = []string{"rutracker.org", "www.rutracker.org"}
+	reTopicID      = regexp.MustCompile(`^\d+$`)
+	rutrackerPaths = []string{"viewtopic.php", "dl.php"}
+)
+
+// Kind tells how a link has to be fetched.
+type Kind int
+
+const (
+	// KindRutracker links are downloaded through the authenticated Rutracker client.
+	KindRutracker Kind = iota
+	// KindDirect links point to a .torrent file on a trusted host and are fetched over plain HTTP.
+	KindDirect
+)
+
+// Link is a resolved torrent link.
+type Link struct {
+	Kind Kind
+	// URL is the relative "dl.php?t=N" form for Rutracker links and the absolute URL otherwise.
+	URL string
+	// TopicID is set for Rutracker links.
+	TopicID string
+}
+
+// Resolve recognises a Rutracker topic or download link, either absolute or
+// in the relative form returned by the Rutracker search, or an http(s) URL of
+// a .torrent file on one of the trusted hosts.
+func Resolve(text string, trustedHosts []string) (Link, error) {
+	raw := strings.TrimSpace(text)
+	u, err := url.Parse(raw)
+	if err != nil || strings.ContainsAny(raw, " \t\n") {
+		return Link{}, fmt.Errorf("not a link")
+	}
+
+	if u.Host == "" && u.Scheme == "" {
+		return resolveRutracker(u, u.Path)
+	}
+	if err := checkScheme(u); err != nil {
+		return Link{}, err
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if slices.Contains(rutrackerHosts, host) {
+		page, ok := strings.CutPrefix(u.Path, "/forum/")
+		if !ok {
+			return Link{}, fmt.Errorf("not a Rutracker topic link")
+		}
+		return resolveRutracker(u, page)
+	}
+
+	if err := checkTrusted(u, trustedHosts); err != nil {
+		return Link{}, err
+	}
+	if !strings.HasSuffix(strings.ToLower(u.Path), ".torrent") {
+		return Link{}, fmt.Errorf("link does not point to a .torrent file")
+	}
+	return Link{Kind: KindDirect, URL: u.String()}, nil
+}
+
+func resolveRutracker(u *url.URL, page string) (Link, error) {
+	if !slices.Contains(rutrackerPaths, page) {
+		return Link{}, fmt.Errorf("not a Rutracker topic link")
+	}
+	topicID := u.Query().Get("t")
+	if !reTopicID.MatchString(topicID) {
+		return Link{}, fmt.Errorf("Rutracker link has no topic ID")
+	}
+	return Link{Kind: KindRutracker, URL: "dl.php?t=" + topicID, TopicID: topicID}, nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported link scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// checkTrusted accepts an http(s) URL on one of the trusted hosts.
+func checkTrusted(u *url.URL, trustedHosts []string) error {
+	if err := checkScheme(u); err != nil {
+		return err
+	}
+	if host := strings.ToLower(u.Hostname()); !isTrustedHost(host, trustedHosts) {
+		return fmt.Errorf("host %s is not trusted", host)
+	}
+	return nil
+}
+
+// isTrustedHost matches the host against the allowlist; an entry also covers its subdomains.
+func isTrustedHost(host string, trustedHosts []string) bool {
+	for _, trusted := range trustedHosts {
+		trusted = strings.ToLower(strings.TrimSpace(trusted))
+		if trusted == "" {
+			continue
+		}
+		if host == trusted || strings.HasSuffix(host, "."+trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHosts splits a comma-separated host list, dropping empty entries.
+func ParseHosts(s string) []string {
+	var hosts []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			hosts = append(hosts, part)
+		}
+	}
+	return hosts
+}
+
+// httpClient fetches from the trusted hosts only: every redirect must stay on
+// them too, so a trusted host, or anyone in the middle of a plain HTTP fetch,
+// can't send the bot to internal addresses.
+func httpClient(trustedHosts []string) *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if err := checkTrusted(req.URL, trustedHosts); err != nil {
+				return fmt.Errorf("redirect to %s refused: %w", req.URL.Redacted(), err)
+			}
+			return nil
+		},
+	}
+}
+
+// Fetch downloads a direct .torrent link, following redirects only to the
+// trusted hosts.
+func Fetch(link Link, trustedHosts []string) ([]byte, error) {
+	if link.Kind != KindDirect {
+		return nil, fmt.Errorf("not a direct link")
+	}
+	resp, err := httpClient(trustedHosts).Get(link.URL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", link.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", link.URL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxTorrentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", link.URL, err)
+	}
+	if len(data) > MaxTorrentSize {
+		return nil, fmt.Errorf("torrent file at %s is too large", link.URL)
+	}
+	return data, nil
+}
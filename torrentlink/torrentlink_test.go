@@ -0,0 +1,127 @@
+package torrentlink
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveRutracker(t *testing.T) {
+	for _, text := range []string{
+		"https://rutracker.org/forum/viewtopic.php?t=123",
+		"http://www.rutracker.org/forum/dl.php?t=123",
+		" https://rutracker.org/forum/viewtopic.php?t=123&start=30 ",
+		"dl.php?t=123",
+		"viewtopic.php?t=123",
+	} {
+		link, err := Resolve(text, nil)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", text, err)
+			continue
+		}
+		if link.Kind != KindRutracker || link.URL != "dl.php?t=123" || link.TopicID != "123" {
+			t.Errorf("%q: unexpected link %#v", text, link)
+		}
+	}
+}
+
+func TestResolveDirect(t *testing.T) {
+	trusted := []string{"torrents.example.org"}
+
+	link, err := Resolve("https://cdn.torrents.example.org/files/show.torrent", trusted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.Kind != KindDirect || link.URL != "https://cdn.torrents.example.org/files/show.torrent" {
+		t.Errorf("unexpected link %#v", link)
+	}
+}
+
+func TestResolveRejects(t *testing.T) {
+	trusted := []string{"torrents.example.org"}
+	for _, text := range []string{
+		"dune 2021",
+		"https://rutracker.org/forum/index.php",
+		"https://rutracker.org/forum/viewtopic.php?t=abc",
+		"https://evil.example.com/show.torrent",
+		"https://nottorrents.example.org/show.torrent",
+		"https://torrents.example.org/page.html",
+		"ftp://torrents.example.org/show.torrent",
+		"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+	} {
+		if link, err := Resolve(text, trusted); err == nil {
+			t.Errorf("%q: expected error, got %#v", text, link)
+		}
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	hosts := ParseHosts(" Example.org, ,torrents.example.net ")
+	if len(hosts) != 2 || hosts[0] != "example.org" || hosts[1] != "torrents.example.net" {
+		t.Errorf("unexpected hosts %v", hosts)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.torrent" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("d4:infod4:name1:xee"))
+	}))
+	defer srv.Close()
+
+	trusted := []string{"127.0.0.1"}
+	data, err := Fetch(Link{Kind: KindDirect, URL: srv.URL + "/show.torrent"}, trusted)
+	if err != nil || string(data) != "d4:infod4:name1:xee" {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+
+	if _, err := Fetch(Link{Kind: KindDirect, URL: srv.URL + "/missing.torrent"}, trusted); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected status error, got %v", err)
+	}
+	if _, err := Fetch(Link{Kind: KindRutracker, URL: "dl.php?t=1"}, trusted); err == nil {
+		t.Error("expected error for a Rutracker link")
+	}
+}
+
+func TestFetch_Redirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the untrusted host was requested")
+	}))
+	defer internal.Close()
+	// The same server under a name that is not trusted
+	untrusted := strings.Replace(internal.URL, "127.0.0.1", "localhost", 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved.torrent":
+			http.Redirect(w, r, "/show.torrent", http.StatusFound)
+		case "/escape.torrent":
+			http.Redirect(w, r, untrusted+"/rpc", http.StatusFound)
+		case "/scheme.torrent":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/loop.torrent":
+			http.Redirect(w, r, "/loop.torrent", http.StatusFound)
+		default:
+			w.Write([]byte("d4:infod4:name1:xee"))
+		}
+	}))
+	defer srv.Close()
+	trusted := []string{"127.0.0.1"}
+
+	if data, err := Fetch(Link{Kind: KindDirect, URL: srv.URL + "/moved.torrent"}, trusted); err != nil || len(data) == 0 {
+		t.Errorf("expected a redirect on the trusted host to be followed, got %q, %v", data, err)
+	}
+	for path, want := range map[string]string{
+		"/escape.torrent": "not trusted",
+		"/scheme.torrent": "unsupported link scheme",
+		"/loop.torrent":   "stopped after",
+	} {
+		if _, err := Fetch(Link{Kind: KindDirect, URL: srv.URL + path}, trusted); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", path, want, err)
+		}
+	}
+}